@@ -0,0 +1,100 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/platinasystems/goes/internal/shellutils"
+)
+
+// Alias defines the text that replaces the unquoted first word, name, of
+// a command line.
+func (g *Goes) Alias(name, value string) error {
+	if len(name) == 0 || strings.ContainsAny(name, " \t\n=/$`'\"\\|&;()<>") {
+		return fmt.Errorf("%s: invalid alias name", name)
+	}
+	if g.aliases == nil {
+		g.aliases = make(map[string]string)
+	}
+	g.aliases[name] = value
+	return nil
+}
+
+// Unalias removes the named alias.
+func (g *Goes) Unalias(name string) error {
+	if _, found := g.aliases[name]; !found {
+		return fmt.Errorf("%s: not found", name)
+	}
+	delete(g.aliases, name)
+	return nil
+}
+
+// Aliases returns the text of each alias by name.
+func (g *Goes) Aliases() map[string]string {
+	m := make(map[string]string, len(g.aliases))
+	for name, value := range g.aliases {
+		m[name] = value
+	}
+	return m
+}
+
+// expandAlias replaces the first word of the list with the command lines
+// of its alias; then, that of the replacement unless it's an alias already
+// expanded, e.g. ls='ls -F'.
+func (g *Goes) expandAlias(ls shellutils.List) (shellutils.List, error) {
+	var expanded map[string]bool
+	for len(ls.Cmds) > 0 && len(ls.Cmds[0].Cmds) > 0 {
+		cl := ls.Cmds[0]
+		w := cl.Cmds[0]
+		if len(w.Tokens) != 1 || w.Tokens[0].T != shellutils.TokenLiteral ||
+			w.Tokens[0].Q {
+			break
+		}
+		name := w.Tokens[0].V
+		value, found := g.aliases[name]
+		if !found || expanded[name] {
+			break
+		}
+		if expanded == nil {
+			expanded = make(map[string]bool)
+		}
+		expanded[name] = true
+		lines := strings.Split(value, "\n")
+		al, err := shellutils.Parse("", func(string) (string, error) {
+			if len(lines) == 0 {
+				return "", io.EOF
+			}
+			s := lines[0]
+			lines = lines[1:]
+			return s, nil
+		})
+		if err == io.EOF {
+			al = &shellutils.List{}
+		} else if err != nil {
+			return ls, fmt.Errorf("alias %s: %v", name, err)
+		}
+		// the replacement is where the alias was used
+		cmds := al.Cmds
+		for i := range cmds {
+			cmds[i].Pos = w.Pos
+			for j := range cmds[i].Cmds {
+				cmds[i].Cmds[j].Pos = w.Pos
+			}
+		}
+		rest := cl
+		rest.Cmds = cl.Cmds[1:]
+		if n := len(cmds); n > 0 && len(cmds[n-1].Term.Tokens) == 0 {
+			cmds[n-1].Cmds = append(cmds[n-1].Cmds, rest.Cmds...)
+			cmds[n-1].Term = rest.Term
+		} else if len(rest.Cmds) > 0 || len(rest.Term.Tokens) > 0 {
+			cmds = append(cmds, rest)
+		}
+		ls.Cmds = append(cmds, ls.Cmds[1:]...)
+	}
+	return ls, nil
+}
@@ -0,0 +1,94 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package alias
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "alias" }
+
+func (*Command) Usage() string { return "alias [NAME[=VALUE]]..." }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "define or print command aliases",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Define each NAME as an alias of VALUE; or, print the named aliases,
+	or all aliases without arguments, in a form that may be reused as
+	input.
+
+	An unquoted alias NAME that is the first word of a command line is
+	replaced by VALUE, which may itself have arguments, pipes, and lists.
+	The first word of the replacement is also expanded unless it's an
+	alias that was already replaced, so an alias may refer to the
+	command of the same name. An alias defined on a line is used by the
+	following lines.
+
+	The interactive cli defines the aliases of the user's startup
+	script, $HOME/.goesrc.
+
+EXAMPLES
+	alias ll='ls -l' ipr='ip route show'
+	alias ls='ls -F'
+	alias
+
+SEE ALSO
+	unalias`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork | cmd.CantPipe }
+
+func (c *Command) Main(args ...string) error {
+	aliases := c.g.Aliases()
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		for name := range aliases {
+			args = append(args, name)
+		}
+		sort.Strings(args)
+	}
+	var failed bool
+	for _, arg := range args {
+		if eq := strings.Index(arg, "="); eq > 0 {
+			if err := c.g.Alias(arg[:eq], arg[eq+1:]); err != nil {
+				fmt.Fprintln(os.Stderr, "alias:", err)
+				failed = true
+			}
+		} else if value, found := aliases[arg]; found {
+			fmt.Printf("alias %s='%s'\n", arg,
+				strings.Replace(value, "'", `'\''`, -1))
+		} else {
+			fmt.Fprintf(os.Stderr, "alias: %s: not found\n", arg)
+			failed = true
+		}
+	}
+	if failed {
+		return goes.ExitStatus(1)
+	}
+	return nil
+}
@@ -20,7 +20,7 @@ type Command struct{}
 func (Command) String() string { return "!" }
 
 func (Command) Usage() string {
-	return "! COMMAND [ARGS]... [&]"
+	return "! COMMAND [ARGS]..."
 }
 
 func (Command) Apropos() lang.Alt {
@@ -35,41 +35,24 @@ func (Command) Man() lang.Alt {
 DESCRIPTION
 	Sh-bang!
 
-	Command executes in background if last argument ends with '&'.
-	The standard i/o redirections apply.`,
+	Like any other command list, this executes in background if
+	terminated by '&'. The standard i/o redirections apply.`,
 	}
 }
 
 func (Command) Kind() cmd.Kind { return cmd.DontFork }
 
 func (Command) Main(args ...string) error {
-	var background bool
-
 	if len(args) == 0 {
 		return fmt.Errorf("COMMAND: missing")
 	}
 
-	if n := len(args); args[n-1] == "&" {
-		background = true
-		args = args[:n-1]
-	}
-
 	cmd := exec.Command(args[0], args[1:]...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	if background {
-		go func() {
-			err := cmd.Run()
-			if err != nil {
-				fmt.Fprintln(os.Stderr, cmd.Args[0], ": ", err)
-			}
-		}()
-		return nil
-	} else {
-		signal.Ignore(syscall.SIGINT)
-		// FIXME how to kill subprocess with SIGINT
-		return cmd.Run()
-	}
+	signal.Ignore(syscall.SIGINT)
+	// FIXME how to kill subprocess with SIGINT
+	return cmd.Run()
 }
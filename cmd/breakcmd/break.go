@@ -0,0 +1,59 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package breakcmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "break" }
+
+func (*Command) Usage() string { return "break [N]" }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "exit a for, while, or until loop",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Exit the innermost, or N'th enclosing, for, while, or until loop.`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork | cmd.CantPipe }
+
+func (c *Command) Main(args ...string) error {
+	n := 1
+	switch len(args) {
+	case 0:
+	case 1:
+		i64, err := strconv.ParseInt(args[0], 0, 0)
+		if err != nil {
+			return err
+		}
+		if i64 < 1 {
+			return fmt.Errorf("%s: loop count out of range", args[0])
+		}
+		n = int(i64)
+	default:
+		return fmt.Errorf("%v: unexpected", args[1:])
+	}
+	return c.g.Break(n)
+}
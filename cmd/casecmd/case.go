@@ -0,0 +1,255 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package casecmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/internal/shellutils"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct{}
+
+func (Command) String() string { return "case" }
+
+func (Command) Usage() string {
+	return "case WORD in [PATTERN[|PATTERN]...) COMMAND ;;]... esac"
+}
+
+func (Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "execute commands selected by pattern matching",
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Executes the commands of the first item with a PATTERN that matches
+	WORD. Each item is terminated by ';;' or 'esac'.
+
+	Patterns have the following syntax.
+
+	*	matches any sequence of characters
+	?	matches any single character
+	[...]	matches any single character in the set, which may include
+		ranges such as a-z; a leading '!' or '^' negates the set
+	\c	matches character c
+
+EXAMPLES
+	case $MACHINE in
+	platina-mk1 | platina-mk1-bmc)
+		echo mk1 ;;
+	platina-mk2-*)
+		echo mk2 ;;
+	*)
+		echo unknown ;;
+	esac`,
+	}
+}
+
+type item struct {
+	patterns []shellutils.Word
+	list     []func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error
+}
+
+func (c Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func(stdin io.Reader, stdout io.Writer, stderr io.Writer, isFirst bool, isLast bool) error, error) {
+	var items []item
+	// case WORD in
+	cl := ls.Cmds[0]
+	pos := cl.Cmds[0].Pos
+	if len(cl.Cmds) < 2 {
+		return nil, nil, pos.Errorf("case: WORD: missing")
+	}
+	word := cl.Cmds[1]
+	if len(cl.Cmds) < 3 || cl.Cmds[2].String() != "in" {
+		return nil, nil, pos.Errorf("case: 'in': missing")
+	}
+	if len(cl.Cmds) > 3 {
+		cl.Cmds = cl.Cmds[3:]
+		ls.Cmds[0] = cl
+	} else {
+		ls.Cmds = ls.Cmds[1:]
+	}
+	for {
+		var err error
+		ls, err = refill(g, ls, pos)
+		if err != nil {
+			return nil, nil, err
+		}
+		cl := ls.Cmds[0]
+		if len(cl.Cmds) == 0 {
+			if cl.Term.String() != ";" {
+				return nil, nil, cl.Pos.Errorf("Unexpected '%s'",
+					cl.Term.String())
+			}
+			ls.Cmds = ls.Cmds[1:]
+			continue
+		}
+		if cl.Cmds[0].String() == "esac" {
+			if !cl.IsRedirects(1) {
+				return nil, nil, cl.Cmds[1].Pos.Errorf(
+					"unexpected text after esac")
+			}
+			break
+		}
+		var (
+			it  item
+			end bool
+		)
+		ls, err = parsePatterns(g, ls, pos, &it)
+		if err != nil {
+			return nil, nil, err
+		}
+		for !end {
+			ls, err = refill(g, ls, pos)
+			if err != nil {
+				return nil, nil, err
+			}
+			cl := ls.Cmds[0]
+			if len(cl.Cmds) == 0 {
+				// e.g. a line with only ;;
+				end = cl.Term.String() == ";;"
+				ls.Cmds = ls.Cmds[1:]
+				continue
+			}
+			if cl.Cmds[0].String() == "esac" {
+				break
+			}
+			nextls, term, runfun, err := g.ProcessList(ls)
+			if err != nil {
+				return nil, nil, err
+			}
+			it.list = append(it.list, runfun)
+			ls = *nextls
+			end = term.String() == ";;"
+		}
+		items = append(items, it)
+	}
+	blockfun, err := makeBlockFunc(g, word, items)
+
+	return &ls, blockfun, err
+}
+
+// refill prompts for more input if the list is empty.
+func refill(g *goes.Goes, ls shellutils.List, pos shellutils.Pos) (shellutils.List, error) {
+	for len(ls.Cmds) == 0 {
+		newls, err := g.ParseBlock("case>", pos, "esac")
+		if err != nil {
+			return ls, err
+		}
+		ls = *newls
+	}
+	return ls, nil
+}
+
+// parsePatterns moves the '|' separated patterns preceding ')' from the
+// beginning of the list to the item.
+func parsePatterns(g *goes.Goes, ls shellutils.List, pos shellutils.Pos, it *item) (shellutils.List, error) {
+	for {
+		var err error
+		ls, err = refill(g, ls, pos)
+		if err != nil {
+			return ls, err
+		}
+		cl := ls.Cmds[0]
+		words := cl.Cmds
+		if len(it.patterns) == 0 && len(words) > 0 &&
+			words[0].Unquoted("(") {
+			words = words[1:]
+		}
+		paren := -1
+		for i, w := range words {
+			if w.Unquoted(")") {
+				paren = i
+				break
+			}
+		}
+		if paren < 0 {
+			// PATTERN |
+			if len(words) != 1 || cl.Term.String() != "|" {
+				return ls, cl.Pos.Errorf("case: %s: expected ')'",
+					wordsString(words))
+			}
+			it.patterns = append(it.patterns, words[0])
+			ls.Cmds = ls.Cmds[1:]
+			continue
+		}
+		if paren != 1 {
+			return ls, words[0].Pos.Errorf("case: %s: unexpected",
+				wordsString(words[:paren]))
+		}
+		it.patterns = append(it.patterns, words[0])
+		if len(words) > 2 {
+			cl.Cmds = words[2:]
+			ls.Cmds[0] = cl
+		} else if cl.Term.String() == ";;" {
+			// PATTERN) ;;
+			cl.Cmds = nil
+			ls.Cmds[0] = cl
+		} else {
+			ls.Cmds = ls.Cmds[1:]
+		}
+		return ls, nil
+	}
+}
+
+func wordsString(words []shellutils.Word) string {
+	s := ""
+	for i, w := range words {
+		if i > 0 {
+			s += " "
+		}
+		s += w.String()
+	}
+	return s
+}
+
+func runList(pipeline []func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	for _, runent := range pipeline {
+		err := runent(stdin, stdout, stderr)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func makeBlockFunc(g *goes.Goes, word shellutils.Word, items []item) (func(stdin io.Reader, stdout io.Writer, stderr io.Writer, isFirst bool, isLast bool) error, error) {
+	runfun := func(stdin io.Reader, stdout io.Writer, stderr io.Writer, isFirst bool, isLast bool) error {
+		s, err := word.Expand(g)
+		if err != nil {
+			return err
+		}
+		g.Status = nil
+		for _, it := range items {
+			for _, pattern := range it.patterns {
+				p, err := pattern.Pattern(g)
+				if err != nil {
+					return err
+				}
+				match, err := shellutils.Match(p, s)
+				if err != nil {
+					return fmt.Errorf("case: %s: %v",
+						pattern.String(), err)
+				}
+				if match {
+					return runList(it.list, stdin, stdout, stderr)
+				}
+			}
+		}
+		return nil
+	}
+	return runfun, nil
+}
+
+func (Command) Main(args ...string) error {
+	return errors.New("internal error")
+}
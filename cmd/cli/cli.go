@@ -8,13 +8,16 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"os/signal"
+	"os/user"
+	"path/filepath"
+	"strings"
 	"syscall"
 
 	"github.com/platinasystems/goes"
 	"github.com/platinasystems/goes/cmd"
 	"github.com/platinasystems/goes/cmd/cli/internal/liner"
 	"github.com/platinasystems/goes/cmd/cli/internal/notliner"
+	"github.com/platinasystems/goes/cmd/history"
 	"github.com/platinasystems/goes/cmd/resize"
 	"github.com/platinasystems/goes/lang"
 	"github.com/platinasystems/goes/internal/flags"
@@ -30,12 +33,14 @@ type parsedCommand struct {
 type Command struct {
 	Prompt string
 	g      *goes.Goes
+	// depth of the running, e.g. sourced, interpreters
+	depth int
 }
 
 func (*Command) String() string { return "cli" }
 
 func (*Command) Usage() string {
-	return "cli [-x] [-p PROMPT] [URL]"
+	return "cli [-nx] [-p PROMPT] [URL [ARG]...]"
 }
 
 func (*Command) Apropos() lang.Alt {
@@ -60,10 +65,43 @@ DESCRIPTION
 	associatated commands to provide semantics without altering the basic
 	syntax.
 
-	The '-x' flag enables trace of each interpreted command.
+	A COMMAND that isn't a goes command, function, or builtin runs the
+	external program of that name found in the directories of $PATH, or
+	the named file if it has a slash. The program's environment includes
+	the shell variables. The status, $?, is 127 if the program isn't found
+	and 126 if it can't be executed.
+
+	The '-x' flag enables trace of each interpreted command, like
+	'set -x'. See 'man set' for this and the other shell options.
+
+	At the start of an interactive session, the cli runs the commands of
+	the user's startup script, $HOME/.goesrc, if any; e.g. to define
+	aliases. See 'man alias'.
+
+	The '-n' flag reads and parses every command, including those of
+	blocks like if, while, for, case, and function, without running any.
+	Each syntax error is reported as URL:LINE:COLUMN followed by the
+	message, and the exit status is 2 if there were any. This also
+	checks a script run as 'goes -n SCRIPT', which needn't begin with
+	'#!/usr/bin/goes', e.g. one that's sourced. A script that ends in a
+	block reports the EOF at the keyword of the block that isn't closed.
 
 	With 'URL', commands are sourced from the reference instead of prompted
-	tty input.
+	tty input. Any following arguments are the script's positional
+	parameters.
+
+HISTORY
+	The interactive cli saves each command line in a history file that's
+	shared with the user's other sessions. The up and down arrows recall
+	previous lines, Ctrl-R searches them, and these refer to them within
+	a command line.
+
+		!!	the previous command line
+		!N	command line N of the history
+		!-N	the Nth previous command line
+		!PREFIX	the most recent command line beginning with PREFIX
+
+	See 'man history'.
 
 COMMENTS
 	Hash tag prefaced comments are ignored, e.g.:
@@ -102,6 +140,95 @@ QUOTATION
 
 		echo 'hello "beautiful world"'
 
+PARAMETERS
+	These special variables are set by the cli.
+
+		$0	the script URL or program name
+		$1...$9	the positional parameters of the script or function,
+			${10} and beyond must be braced
+		$#	the number of positional parameters
+		$@	the positional parameters as separate arguments, even
+			when double quoted
+		$*	the positional parameters; when double quoted, a
+			single argument separated by the first character of IFS
+		$?	the exit status of the last command
+		$$	the process id of the cli
+		$!	the process id of the last background command
+
+	The cli also sets PIPESTATUS to the exit status of each command of
+	the last pipeline, e.g. "1 0" after "false | true". ${PIPESTATUS[N]}
+	is that of command N, counting from 0. A failed command that isn't
+	the last of its pipeline is reported with its name.
+
+	See also 'man shift'.
+
+PARAMETER EXPANSION
+	A braced parameter may be modified by one of these operators. WORD
+	may itself include quotes and expansions; PATTERN is as described in
+	PATHNAME EXPANSION.
+
+		${NAME:-WORD}	WORD if NAME is unset or empty
+		${NAME:=WORD}	as above, also assigning WORD to NAME
+		${NAME:?WORD}	fail with message WORD if NAME is unset or empty,
+				which exits a script
+		${NAME:+WORD}	WORD if NAME is set and not empty
+		${#NAME}	the number of characters in the value
+		${NAME#PATTERN}	remove the shortest matching prefix
+		${NAME##PATTERN}
+				remove the longest matching prefix
+		${NAME%PATTERN}	remove the shortest matching suffix
+		${NAME%%PATTERN}
+				remove the longest matching suffix
+		${NAME/PATTERN/WORD}
+				replace the first longest match with WORD
+		${NAME//PATTERN/WORD}
+				replace every match with WORD
+
+	e.g.:
+		f=/var/log/messages.1.gz
+		echo ${f##*/} ${f%.gz} ${LEVEL:-info}
+
+ARITHMETIC EXPANSION
+	The value of an integer expression, with C-like operators, may
+	replace an argument or variable value.
+
+		echo $(( (temp - 20) * 0xff / 80 ))
+		i=$((i + 1))
+
+	See 'man let' for the operators.
+
+COMMAND SUBSTITUTION
+	The output of a command list, with trailing newlines removed, may
+	replace an argument or variable value.
+
+		ip=$(hget platina eth0.ip)
+		echo kernel $(uname -r)
+
+	The older backquoted form is also supported.
+
+	The list runs in a subshell, a copy of the shell's variables and
+	functions, so its assignments and exit don't change the shell. The
+	status, $?, of a command of only assignments is that of its last
+	substitution.
+
+	Unquoted substitutions are split into separate arguments at spaces,
+	tabs, and newlines (or the characters of IFS); double quoted
+	substitutions are not.
+
+PATHNAME EXPANSION
+	Unquoted arguments with these pattern characters are replaced by the
+	sorted names of matching files, if any.
+
+		*	matches any sequence of characters
+		?	matches any single character
+		[...]	matches any single character in the set
+
+	e.g.:
+		cat /sys/class/net/*/address
+
+	Names beginning with '.' are only matched by a literal '.'. Quoted
+	or escaped pattern characters are not expanded.
+
 SPECIAL CHARACTERS
 	The command may encode these special characters.
 
@@ -144,8 +271,37 @@ OPTIONS
 		Read command script upto LABEL as stdin. If LABEL is prefaced
 		by '-', the leading whitespace is trimmed from each line.
 
-	Note: unlike other shells, there must be a space or equal ('=')
-	between the redirection symbols and URL or LABEL.
+	<<< WORD
+		Read WORD, followed by a newline, as stdin.
+
+	The output redirections apply to stderr rather than stdout if
+	prefaced by 2, e.g.:
+
+		2> URL	Redirect stderr to URL.
+		2>> URL	Append stderr to URL.
+
+	>&2
+	1>&2	Redirect stdout to stderr.
+
+	2>&1	Redirect stderr to stdout, e.g. to pipe both.
+
+	&> URL
+	&>> URL
+		Redirect or append both stdout and stderr to URL.
+
+	Redirections are applied in order, so,
+		COMMAND > URL 2>&1
+	writes both to URL, whereas,
+		COMMAND 2>&1 > URL
+	writes stderr to the original stdout.
+
+	Any COMMAND of a pipeline may have redirections, as may a block after
+	its closing keyword, e.g.:
+
+		while read a b; do echo $b; done < /proc/partitions
+
+	The URL or LABEL may follow the redirection symbols with or without
+	space or equal ('=').
 
 PIPES
 	The COMMAND output may be piped to the input of another COMMAND, e.g.:
@@ -153,12 +309,25 @@ PIPES
 		ls -Lr |
 		more
 
-	The COMMAND pipeline may redirect input and output of the first and
-	last commands respectively, e.g.:
+	The COMMAND pipeline may redirect input and output of any command,
+	e.g.:
 
 		cat <<- EOF | wc -l > lines.txt
 			...
-		EOF`,
+		EOF
+
+		dmesg 2>&1 | grep -i error
+
+BACKGROUND JOBS
+	A command list terminated by '&' runs in the background with stdin
+	from /dev/null while the cli continues with the next command, e.g.:
+
+		tftp -g -r image 10.0.0.1 &
+		wait $!
+
+	The process id of the last command forked by the most recent job is
+	available as $!. See also 'man jobs', 'man wait', 'man fg', and
+	'man kill'.`,
 	}
 }
 
@@ -173,6 +342,10 @@ func (c *Command) Main(args ...string) error {
 			Close()
 		}
 		isScript bool
+		// the prompter reads os.Stdin
+		stdin bool
+		// name of the script in syntax errors
+		name string
 	)
 
 	if c.g == nil {
@@ -194,19 +367,50 @@ func (c *Command) Main(args ...string) error {
 		}
 	}()
 
-	flag, args := flags.New(args, "-f", "-x", "-", "-no-liner")
+	c.depth++
+	defer func() {
+		c.depth--
+		if c.depth == 0 {
+			c.g.RunTrap("EXIT")
+		}
+	}()
+
+	// options precede the URL and its positional parameters
+	var params []string
+	for i, arg := range args {
+		if arg == "-" || !strings.HasPrefix(arg, "-") {
+			params = args[i+1:]
+			args = args[:i+1]
+			break
+		}
+	}
+	flag, args := flags.New(args, "-f", "-n", "-x", "-", "-no-liner")
 	switch len(args) {
 	case 0:
 		switch {
 		case flag.ByName["-"]:
 			prompter = notliner.New(os.Stdin, nil)
 			isScript = true
+			stdin = true
+			name = "<stdin>"
+			if len(params) > 0 {
+				arg0 := c.g.Getenv("0")
+				saved := c.g.SetParams(append([]string{arg0},
+					params...)...)
+				defer c.g.SetParams(saved...)
+			}
 		case flag.ByName["-no-liner"]:
 			prompter = notliner.New(os.Stdin, os.Stdout)
+			stdin = true
+			defer c.g.SetInteractive(c.g.SetInteractive(true))
 		default:
+			defer c.g.SetInteractive(c.g.SetInteractive(true))
 			if _, found := c.g.ByName["resize"]; !found {
 				c.g.ByName["resize"] = resize.Command{}
 			}
+			if _, found := c.g.ByName["history"]; !found {
+				c.g.ByName["history"] = &history.Command{}
+			}
 			prompter = liner.New(c.g)
 			defer prompter.Close()
 		}
@@ -219,14 +423,25 @@ func (c *Command) Main(args ...string) error {
 		prompter = notliner.New(script, nil)
 		defer prompter.Close()
 		isScript = true
+		name = args[0]
+		saved := c.g.SetParams(append([]string{args[0]}, params...)...)
+		defer c.g.SetParams(saved...)
 	default:
 		return fmt.Errorf("%v: unexpected", args[1:])
 	}
 
-	if flag.ByName["-f"] && c.g.Verbosity < goes.VerboseVerify {
-		c.g.Verbosity = goes.VerboseVerify
+	if flag.ByName["-x"] || flag.ByName["-f"] {
+		c.g.SetOption("xtrace", true)
 	}
-	if c.g.Catline == nil {
+	// a script, e.g. sourced, reads its own lines then resumes those of
+	// its caller
+	catline, line, catstdin := c.g.Catline, c.g.Line, c.g.CatlineStdin
+	defer func() {
+		c.g.Catline, c.g.Line, c.g.CatlineStdin = catline, line, catstdin
+	}()
+	if isScript || c.g.Catline == nil {
+		c.g.Line = 0
+		c.g.CatlineStdin = stdin
 		c.g.Catline = func(prompt string) (string, error) {
 			s, err := prompter.Prompt(prompt)
 			if err != nil {
@@ -235,7 +450,16 @@ func (c *Command) Main(args ...string) error {
 			return s, nil
 		}
 	}
-	signal.Ignore(syscall.SIGINT)
+	if flag.ByName["-n"] {
+		if c.check(name) > 0 {
+			return goes.ExitStatus(2)
+		}
+		return nil
+	}
+	if !isScript && catline == nil && c.depth == 1 {
+		c.startup()
+	}
+	c.g.IgnoreSignal(syscall.SIGINT)
 readCommandLoop:
 	for {
 		prompt := c.Prompt
@@ -247,18 +471,21 @@ readCommandLoop:
 				}
 			}
 		}
-		cl, err := shellutils.Parse(prompt, c.g.Catline)
+		cl, err := c.g.Parse(prompt)
 		if err != nil {
 			if err == io.EOF {
 				return nil
 			}
-			fmt.Fprintln(os.Stderr, err)
+			perror(name, err)
 			if isScript && !flag.ByName["-f"] {
 				return nil
 			}
 			continue readCommandLoop
 		}
-		err = c.runList(*cl, flag, isScript)
+		err = c.runList(*cl, flag, isScript, name)
+		if c.g.Exiting() {
+			return goes.ExitStatus(goes.ExitCode(c.g.Status))
+		}
 		if err != nil {
 			if err == io.EOF {
 				return nil
@@ -268,19 +495,22 @@ readCommandLoop:
 	}
 }
 
-func (c *Command) runList(ls shellutils.List, flag *flags.Flags, isScript bool) (err error) {
+func (c *Command) runList(ls shellutils.List, flag *flags.Flags, isScript bool, name string) (err error) {
 	// loop for each pipeline in command list
 	for len(ls.Cmds) != 0 {
 		newls, _, runner, err := c.g.ProcessList(ls)
 		if err == nil {
 			err = runner(os.Stdin, os.Stdout, os.Stderr)
 		}
+		if c.g.Exiting() {
+			return nil
+		}
 		if err != nil {
 			if err == io.EOF {
 				return err
 			}
 			if err.Error() != "exit status 1" {
-				fmt.Fprintln(os.Stderr, err)
+				perror(name, err)
 			}
 			if isScript && !flag.ByName["-f"] {
 				return nil
@@ -295,3 +525,96 @@ func (c *Command) runList(ls shellutils.List, flag *flags.Flags, isScript bool)
 	}
 	return nil
 }
+
+// startup runs the user's startup script, $HOME/.goesrc, e.g. with alias
+// definitions, before the first prompt of the interactive cli.
+func (c *Command) startup() {
+	home := c.g.Getenv("HOME")
+	if len(home) == 0 {
+		u, err := user.Current()
+		if err != nil {
+			return
+		}
+		home = u.HomeDir
+	}
+	rc := filepath.Join(home, ".goesrc")
+	if _, err := os.Stat(rc); err != nil {
+		return
+	}
+	if err := c.Main(rc); err != nil {
+		if _, quiet := err.(goes.ExitStatus); !quiet {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+}
+
+// keywords may only continue or end a block.
+var keywords = []string{"then", "elif", "else", "fi", "do", "done", "esac", "}"}
+
+// check parses the script, including its blocks, without running any
+// commands and reports each syntax error. It returns the number of errors.
+func (c *Command) check(name string) int {
+	var n int
+	for {
+		ls, err := c.g.Parse("")
+		if err == io.EOF {
+			return n
+		}
+		if err != nil {
+			perror(name, err)
+			n++
+			if _, ok := err.(*shellutils.SyntaxError); ok {
+				continue
+			}
+			return n
+		}
+		heredocs := ls.Heredocs()
+	checkList:
+		for len(ls.Cmds) != 0 {
+			cl := ls.Cmds[0]
+			if len(cl.Cmds) > 0 {
+				kw := cl.Cmds[0].String()
+				for _, s := range keywords {
+					if kw == s {
+						perror(name, cl.Pos.Errorf(
+							"Unexpected '%s'", kw))
+						n++
+						ls.Cmds = ls.Cmds[1:]
+						continue checkList
+					}
+				}
+			}
+			ls, _, _, err = c.g.ProcessList(*ls)
+			if err != nil {
+				perror(name, err)
+				n++
+				break
+			}
+		}
+		// skip the here documents of the commands that weren't run
+		for _, r := range heredocs {
+			for {
+				s, err := c.g.Catline("")
+				if err != nil {
+					break
+				}
+				c.g.Line++
+				if r.Op == "<<-" {
+					s = strings.TrimLeft(s, " \t")
+				}
+				if s == r.Target {
+					break
+				}
+			}
+		}
+	}
+}
+
+// perror prints the error, with the script name preceding the position of
+// syntax errors.
+func perror(name string, err error) {
+	if _, ok := err.(*shellutils.SyntaxError); ok && len(name) > 0 {
+		fmt.Fprint(os.Stderr, name, ":")
+	}
+	fmt.Fprintln(os.Stderr, err)
+}
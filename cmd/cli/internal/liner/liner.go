@@ -8,7 +8,6 @@ package liner
 
 import (
 	"bufio"
-	"bytes"
 	"fmt"
 	"os"
 	"strings"
@@ -20,6 +19,7 @@ import (
 	"github.com/platinasystems/goes"
 	"github.com/platinasystems/goes/cmd/cli/internal/notliner"
 	"github.com/platinasystems/goes/internal/fields"
+	"github.com/platinasystems/goes/internal/histfile"
 	"github.com/platinasystems/goes/internal/nocomment"
 	"github.com/platinasystems/goes/internal/pizza"
 	"github.com/platinasystems/liner"
@@ -28,20 +28,17 @@ import (
 const woliner = false
 
 type Liner struct {
-	history struct {
-		buf   *bytes.Buffer
-		lines []string
-		i     int
-	}
+	history  *histfile.History
 	fallback *notliner.Prompter
 	goes     *goes.Goes
 	s        *liner.State
+	// warned of a history file error
+	warned bool
 }
 
 func New(g *goes.Goes) *Liner {
 	l := new(Liner)
-	l.history.buf = new(bytes.Buffer)
-	l.history.lines = make([]string, 0, 1<<6)
+	l.history = histfile.New(histfile.Name(g.Getenv), histfile.Size(g.Getenv))
 	if woliner {
 		l.fallback = notliner.New(os.Stdin, os.Stdout)
 	}
@@ -62,6 +59,10 @@ func (l *Liner) complete(line string) (lines []string) {
 		return
 	}
 	args := pl.Slices[len(pl.Slices)-1]
+	if strings.HasSuffix(line, " ") {
+		// complete the next word rather than the last
+		args = append(args, "")
+	}
 	pr, pw, err := os.Pipe()
 	if err != nil {
 		return
@@ -156,37 +157,38 @@ func (l *Liner) Prompt(prompt string) (string, error) {
 		l.goes.Status = status
 	}
 
-	if len(l.history.lines) > 0 {
-		l.history.buf.Reset()
-		if len(l.history.lines) < cap(l.history.lines) {
-			for i := 0; i < l.history.i; i++ {
-				fmt.Fprintln(l.history.buf, l.history.lines[i])
-			}
-		} else {
-			for i := l.history.i + 1; ; i++ {
-				i &= cap(l.history.lines) - 1
-				if i == l.history.i {
-					break
-				}
-				fmt.Fprintln(l.history.buf, l.history.lines[i])
-			}
-		}
-		l.s.ReadHistory(l.history.buf)
+	// reload the history shared with other sessions for the up arrow
+	// and Ctrl-R reverse search
+	l.warn(l.history.Load())
+	if lines := l.history.Lines(); len(lines) > 0 {
+		l.s.ReadHistory(strings.NewReader(strings.Join(lines, "\n")))
 	}
 
 	line, err := l.s.Prompt(prompt)
 
 	if err == nil {
-		if len(l.history.lines) < cap(l.history.lines) {
-			l.history.lines = append(l.history.lines, line)
-		} else {
-			l.history.lines[l.history.i] = line
+		s, err := l.history.Expand(line)
+		if err != nil {
+			return "", err
+		}
+		if s != line {
+			// show the command with its history references expanded
+			fmt.Println(s)
+			line = s
 		}
-		l.history.i++
-		l.history.i &= cap(l.history.lines) - 1
+		l.warn(l.history.Add(line))
 	} else if err == liner.ErrNotTerminalOutput {
 		l.fallback = notliner.New(os.Stdin, os.Stdout)
 		line, err = l.fallback.Prompt(prompt)
 	}
 	return line, err
 }
+
+// warn of the first history file error; after which the history may only
+// be kept in memory.
+func (l *Liner) warn(err error) {
+	if err != nil && !l.warned {
+		fmt.Fprintln(os.Stderr, "history:", err)
+		l.warned = true
+	}
+}
@@ -5,13 +5,21 @@
 package daemons
 
 import (
+	"encoding/gob"
 	"fmt"
+	"io"
+	"log/syslog"
 	"os"
+	"time"
 
 	"github.com/platinasystems/atsock"
 	"github.com/platinasystems/goes"
 	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/internal/duration"
+	"github.com/platinasystems/goes/internal/flags"
+	"github.com/platinasystems/goes/internal/parms"
 	"github.com/platinasystems/goes/lang"
+	"github.com/platinasystems/log"
 )
 
 var Admin = &goes.Goes{
@@ -40,7 +48,8 @@ type Stop struct{}
 func (Log) String() string { return "log" }
 
 func (Log) Usage() string {
-	return "daemon log [TEXT]..."
+	return "daemon log [-f] [-n N] [-daemon NAME] [-since TIME] " +
+		"[-p PRIORITY] [TEXT]..."
 }
 
 func (Log) Apropos() lang.Alt {
@@ -49,19 +58,123 @@ func (Log) Apropos() lang.Alt {
 	}
 }
 
+func (Log) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Append any TEXT to the log of goes-daemons, then show the entries of
+	it and its daemons that match the options.
+
+	These are from the persistent log of each daemon, NAME.log, in the
+	directory configured by the machine, e.g. /var/log/goes. Each is
+	rotated to gzip compressed NAME.log.1.gz, NAME.log.2.gz, etc. when
+	full. Without options, only the entries of the current, unrotated,
+	files are shown. Without a configured directory, these are just the
+	recent entries kept in memory.
+
+OPTIONS
+	-f		follow, i.e. show entries as they're logged until
+			interrupted
+	-n N		show only the last N entries
+	-daemon NAME	show only the entries of the named daemon; that of
+			goes-daemons itself is "goes-daemons"
+	-since TIME	show only the entries since TIME, which is either a
+			DURATION ago, e.g. 90s or 10m, or a local date and
+			time like "2006-01-02 15:04:05", 2006-01-02, or 15:04
+	-p PRIORITY	show only the entries of PRIORITY or more severe,
+			one of: emerg, alert, crit, err, warn, note, info, or
+			debug
+
+EXAMPLES
+	daemon log -n 20
+	daemon log -daemon redisd -since 1h
+	daemon log -f -p err`,
+	}
+}
+
 func (Log) Main(args ...string) error {
-	var s string
-	cl, err := atsock.NewRpcClient(sockname)
+	flag, args := flags.New(args, "-f")
+	parm, args := parms.New(args, "-n", "-daemon", "-since", "-p")
+	q := logQuery{
+		Follow:   flag.ByName["-f"],
+		Daemon:   parm.ByName["-daemon"],
+		Priority: int(syslog.LOG_DEBUG),
+	}
+	if s := parm.ByName["-n"]; len(s) > 0 {
+		if _, err := fmt.Sscan(s, &q.N); err != nil || q.N < 0 {
+			return fmt.Errorf("%s: invalid N", s)
+		}
+	}
+	if s := parm.ByName["-since"]; len(s) > 0 {
+		t, err := since(s)
+		if err != nil {
+			return err
+		}
+		q.Since = t
+	}
+	if s := parm.ByName["-p"]; len(s) > 0 {
+		pri, found := log.PriorityByName[s]
+		if !found {
+			return fmt.Errorf("%s: invalid PRIORITY", s)
+		}
+		q.Priority = int(pri)
+	}
+	if len(args) > 0 {
+		var s string
+		cl, err := atsock.NewRpcClient(sockname)
+		if err != nil {
+			return err
+		}
+		err = cl.Call("Daemons.Log", args, &s)
+		cl.Close()
+		if err != nil {
+			return err
+		}
+	}
+	conn, err := atsock.Dial(logsockname)
 	if err != nil {
 		return err
 	}
-	defer cl.Close()
-	if err = cl.Call("Daemons.Log", args, &s); err == nil {
-		os.Stdout.WriteString(s)
+	defer conn.Close()
+	if err = gob.NewEncoder(conn).Encode(&q); err != nil {
+		return err
 	}
+	_, err = io.Copy(os.Stdout, conn)
 	return err
 }
 
+// since returns the time of a DURATION ago or a local date and time.
+func since(s string) (time.Time, error) {
+	if d, err := duration.Parse(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	now := time.Now()
+	for _, layout := range []string{
+		"2006-01-02 15:04:05",
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04",
+		"2006-01-02",
+	} {
+		t, err := time.ParseInLocation(layout, s, time.Local)
+		if err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		t, err := time.ParseInLocation(layout, s, time.Local)
+		if err == nil {
+			// today
+			y, m, d := now.Date()
+			return time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(),
+				0, time.Local), nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%s: invalid TIME", s)
+}
+
 func (Restart) String() string { return "restart" }
 
 func (Restart) Usage() string {
@@ -123,6 +236,40 @@ func (Status) Apropos() lang.Alt {
 	}
 }
 
+func (Status) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	List the PID, arguments, and state of each daemon, one of:
+
+		running
+		starting	running, but not yet ready
+		backoff		waiting to restart after its exit
+		crash-loop	waiting to restart after many consecutive exits
+		waiting		waiting for the daemons that it's configured to
+				start After or Requires to be ready
+		exited		done, not to be restarted by its policy
+		failed		exited with an error, not to be restarted by its
+				policy or after too many consecutive restarts;
+				or, a daemon that it Requires failed
+
+	With cgroup v2, a running daemon also has the CPU time and RSS of the
+	group that it's started in, goes-daemons/NAME-XXXXXX. One that's
+	started without a limit of its config, e.g. memory.max of a group
+	without the memory controller, or without cgroup v2, has the
+	"without" limits; the reason of each is logged.
+
+	A daemon configured with Liveness that misses its heartbeat is killed
+	and restarted by its policy; its status has the number of times as
+	"unhealthy N".
+
+	'daemon stop' stops each daemon before those it's After or Requires.
+
+	A daemon that's not running keeps its last PID for 'daemon restart'
+	or 'daemon stop'.`,
+	}
+}
+
 func (Status) Main(args ...string) error {
 	var s string
 	cl, err := atsock.NewRpcClient(sockname)
@@ -0,0 +1,212 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package daemons
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/platinasystems/log"
+)
+
+// cgroupInit returns the cgroup v2 group of the daemons, goes-daemons,
+// nested in that of this process with the cpu, memory, and pids controllers
+// enabled, where available, for its children; or, an empty string if the
+// unified hierarchy isn't mounted.
+func cgroupInit() string {
+	f, err := os.Open("/proc/mounts")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	mnt := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 2 && fields[2] == "cgroup2" {
+			mnt = fields[1]
+			break
+		}
+	}
+	if len(mnt) == 0 {
+		return ""
+	}
+	// e.g. 0::/system.slice/goes.service
+	own := "/"
+	if buf, err := ioutil.ReadFile("/proc/self/cgroup"); err == nil {
+		for _, line := range strings.Split(string(buf), "\n") {
+			if strings.HasPrefix(line, "0::") {
+				own = line[3:]
+			}
+		}
+	}
+	parent := filepath.Join(mnt, own)
+	dn := filepath.Join(parent, sockname)
+	if err = os.Mkdir(dn, 0755); err != nil && !os.IsExist(err) {
+		log.Print("daemon", "err", err)
+		return ""
+	}
+	// one at a time, since any may be unavailable or owned by cgroup v1
+	for _, c := range []string{"+cpu", "+memory", "+pids"} {
+		for _, dn := range []string{parent, dn} {
+			cgroupWrite(dn, "cgroup.subtree_control", c)
+		}
+	}
+	return dn
+}
+
+// cgroupLimit is a cgroup v2 interface file and its value; with the error
+// of one that the group doesn't have.
+type cgroupLimit struct {
+	fn  string
+	v   int64
+	err error
+}
+
+// cgroupLimits returns those of the config that aren't the kernel's
+// default.
+func cgroupLimits(c Config) []cgroupLimit {
+	var limits []cgroupLimit
+	for _, limit := range []cgroupLimit{
+		{fn: "memory.max", v: c.MemoryMax},
+		{fn: "cpu.weight", v: int64(c.CPUWeight)},
+		{fn: "pids.max", v: int64(c.PidsMax)},
+	} {
+		if limit.v > 0 {
+			limits = append(limits, limit)
+		}
+	}
+	return limits
+}
+
+// cgroupCreate returns a new group, NAME-XXXXXX, of the given parent with
+// the limits of its config, and its open directory to start the daemon in.
+// The group is returned with any limits that it doesn't have, e.g. of a
+// controller that isn't available.
+func cgroupCreate(parent string, c Config, name string) (string, *os.File,
+	[]cgroupLimit, error) {
+	dn, err := ioutil.TempDir(parent, filepath.Base(name)+"-")
+	if err != nil {
+		return "", nil, nil, err
+	}
+	f, err := os.Open(dn)
+	if err != nil {
+		os.Remove(dn)
+		return "", nil, nil, err
+	}
+	var dropped []cgroupLimit
+	for _, limit := range cgroupLimits(c) {
+		limit.err = cgroupWrite(dn, limit.fn, fmt.Sprint(limit.v))
+		if limit.err != nil {
+			dropped = append(dropped, limit)
+		}
+	}
+	return dn, f, dropped, nil
+}
+
+func cgroupWrite(dn, fn, s string) error {
+	err := ioutil.WriteFile(filepath.Join(dn, fn), []byte(s), 0644)
+	if err != nil {
+		// e.g. "write /sys/fs/cgroup/...: invalid argument"
+		if pe, ok := err.(*os.PathError); ok {
+			err = fmt.Errorf("%s: %s: %v", fn, s, pe.Err)
+		}
+	}
+	return err
+}
+
+// cgroupUsage returns the CPU time and anonymous memory, i.e. RSS, of the
+// group; either is empty if unavailable.
+func cgroupUsage(dn string) (cpu, rss string) {
+	if t, found := cgroupStat(dn, "cpu.stat", "usage_usec"); found {
+		cpu = (time.Duration(t) * time.Microsecond).
+			Round(10 * time.Millisecond).String()
+	}
+	if b, found := cgroupStat(dn, "memory.stat", "anon"); found {
+		rss = iec(b)
+	}
+	return
+}
+
+// cgroupStat returns the value of the named line of the group's stat file.
+func cgroupStat(dn, fn, name string) (uint64, bool) {
+	buf, err := ioutil.ReadFile(filepath.Join(dn, fn))
+	if err != nil {
+		return 0, false
+	}
+	for _, line := range strings.Split(string(buf), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == name {
+			v, err := strconv.ParseUint(fields[1], 10, 64)
+			return v, err == nil
+		}
+	}
+	return 0, false
+}
+
+// iec returns the binary, power of 1024, size.
+func iec(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprint(b, "B")
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+// rlimitEnv has the resource limits, RESOURCE:CUR:MAX[,...], that the
+// forked daemon sets before it runs.
+const rlimitEnv = "GOES_DAEMON_RLIMIT"
+
+func init() {
+	if s, found := os.LookupEnv(rlimitEnv); found {
+		os.Unsetenv(rlimitEnv)
+		if err := setrlimits(s); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+}
+
+// rlimits returns the rlimitEnv value of the limits.
+func rlimits(limits map[int]syscall.Rlimit) string {
+	var l []string
+	for resource, rlimit := range limits {
+		l = append(l, fmt.Sprint(resource, ":", rlimit.Cur, ":",
+			rlimit.Max))
+	}
+	sort.Strings(l)
+	return strings.Join(l, ",")
+}
+
+// setrlimits of this process from the rlimitEnv value.
+func setrlimits(s string) error {
+	for _, limit := range strings.Split(s, ",") {
+		var (
+			resource int
+			rlimit   syscall.Rlimit
+		)
+		_, err := fmt.Sscanf(limit, "%d:%d:%d", &resource, &rlimit.Cur,
+			&rlimit.Max)
+		if err == nil {
+			err = syscall.Setrlimit(resource, &rlimit)
+		}
+		if err != nil {
+			return fmt.Errorf("rlimit %s: %v", limit, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package daemons
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCgroupCreate(t *testing.T) {
+	// a directory rather than cgroupfs, where any file may be written
+	c := Config{MemoryMax: 1 << 20, PidsMax: 10}
+	dn, f, dropped, err := cgroupCreate(t.TempDir(), c, "/bin/x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if !strings.HasPrefix(filepath.Base(dn), "x-") || len(dropped) > 0 {
+		t.Errorf("%s: dropped %v", dn, dropped)
+	}
+	for fn, want := range map[string]string{
+		"memory.max": "1048576",
+		"pids.max":   "10",
+	} {
+		if b, err := ioutil.ReadFile(filepath.Join(dn, fn)); err != nil {
+			t.Error(err)
+		} else if string(b) != want {
+			t.Errorf("%s: %q, want %q", fn, b, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dn, "cpu.weight")); err == nil {
+		t.Error("cpu.weight of the default")
+	}
+	// the status of one started without some
+	dm := &daemon{state: running, dropped: []string{"memory.max",
+		"pids.max"}}
+	want := "running, without memory.max, pids.max"
+	if s := dm.status(); s != want {
+		t.Errorf("status %q, want %q", s, want)
+	}
+}
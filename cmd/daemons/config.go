@@ -0,0 +1,140 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package daemons
+
+import (
+	"math/rand"
+	"syscall"
+	"time"
+)
+
+// Config is the supervision of a daemon, by name, in Server.Config. The zero
+// value of each field is its default.
+type Config struct {
+	// Restart is when to restart the daemon after it exits, by default
+	// on failure.
+	Restart RestartPolicy
+	// Backoff is the delay of the first restart, which doubles, with
+	// jitter, for each consecutive restart up to MaxBackoff; by default,
+	// 100ms and 30s.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// ResetAfter is how long the daemon must run for its restarts to no
+	// longer be consecutive, by default 1 minute.
+	ResetAfter time.Duration
+	// CrashLoop is the number of consecutive restarts after which the
+	// daemon is reported in crash-loop, by default 5.
+	CrashLoop int
+	// MaxRestarts is the number of consecutive restarts after which the
+	// daemon is left failed until restarted by admin, by default 10.
+	// It's unlimited if negative.
+	MaxRestarts int
+	// After names the daemons, by args[0], to start this one after they
+	// are ready; unless they fail or aren't ready within ReadyTimeout.
+	After []string
+	// Requires names the daemons that must be ready for this one to
+	// start; it fails instead if any of them fail or aren't ready within
+	// ReadyTimeout.
+	Requires []string
+	// ReadyTimeout is how long to wait for the daemons of After and
+	// Requires, by default 30s.
+	ReadyTimeout time.Duration
+	// Notify is true if the daemon calls Ready once it's ready; otherwise,
+	// it's ready once started.
+	Notify bool
+	// MemoryMax, CPUWeight, and PidsMax are the memory.max, in bytes,
+	// cpu.weight, and pids.max of the daemon's cgroup v2 group; each is
+	// the kernel's default if zero, i.e. unlimited or 100. The daemon is
+	// started without those that can't be set, which are logged and
+	// listed by its status.
+	MemoryMax int64
+	CPUWeight int
+	PidsMax   int
+	// Rlimit has resource limits of the daemon, e.g.
+	//	Rlimit: map[int]syscall.Rlimit{
+	//		syscall.RLIMIT_NOFILE: {Cur: 256, Max: 256},
+	//		syscall.RLIMIT_CORE:   {Cur: 0, Max: 0},
+	//	}
+	Rlimit map[int]syscall.Rlimit
+	// Liveness is the longest time since the daemon started, or last
+	// called Ready or Heartbeat, before it's killed as unhealthy and
+	// restarted by its policy. There's no check if zero.
+	Liveness time.Duration
+	// Critical is true if the health of the machine, by Healthy,
+	// requires that of the daemon.
+	Critical bool
+}
+
+type RestartPolicy int
+
+const (
+	RestartOnFailure RestartPolicy = iota
+	RestartNever
+	RestartAlways
+)
+
+func (p RestartPolicy) String() string {
+	switch p {
+	case RestartOnFailure:
+		return "on-failure"
+	case RestartNever:
+		return "never"
+	case RestartAlways:
+		return "always"
+	}
+	return "invalid"
+}
+
+func (c Config) withDefaults() Config {
+	if c.Backoff <= 0 {
+		c.Backoff = 100 * time.Millisecond
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 30 * time.Second
+	}
+	if c.MaxBackoff < c.Backoff {
+		c.MaxBackoff = c.Backoff
+	}
+	if c.ResetAfter <= 0 {
+		c.ResetAfter = time.Minute
+	}
+	if c.CrashLoop <= 0 {
+		c.CrashLoop = 5
+	}
+	if c.MaxRestarts == 0 {
+		c.MaxRestarts = 10
+	}
+	if c.ReadyTimeout <= 0 {
+		c.ReadyTimeout = 30 * time.Second
+	}
+	return c
+}
+
+// restart returns true if the policy restarts the daemon after it exits
+// with the given error.
+func (c Config) restart(err error) bool {
+	switch c.Restart {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return err != nil
+	}
+	return false
+}
+
+// backoff returns the delay of the restart that follows the given number
+// of consecutive restarts; that's Backoff doubled for each, up to
+// MaxBackoff, less a random jitter of up to half so that daemons that
+// failed together don't restart together.
+func (c Config) backoff(restarts int) time.Duration {
+	t := c.Backoff
+	for i := 0; i < restarts && t < c.MaxBackoff; i++ {
+		t *= 2
+	}
+	if t > c.MaxBackoff {
+		t = c.MaxBackoff
+	}
+	return t - time.Duration(rand.Int63n(int64(t/2)+1))
+}
@@ -0,0 +1,139 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package daemons
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var errExit = errors.New("exit status 1")
+
+func TestBackoff(t *testing.T) {
+	c := Config{
+		Backoff:    100 * time.Millisecond,
+		MaxBackoff: 3 * time.Second,
+	}.withDefaults()
+	max := c.Backoff
+	for restarts := 0; restarts < 10; restarts++ {
+		// doubled for each restart up to MaxBackoff
+		if restarts > 0 {
+			if max *= 2; max > c.MaxBackoff {
+				max = c.MaxBackoff
+			}
+		}
+		// less a random jitter of up to half
+		for i := 0; i < 100; i++ {
+			if got := c.backoff(restarts); got < max/2 || got > max {
+				t.Fatalf("backoff(%d): %v not in [%v, %v]",
+					restarts, got, max/2, max)
+			}
+		}
+	}
+	if max != c.MaxBackoff {
+		t.Errorf("last max %v, want %v", max, c.MaxBackoff)
+	}
+	// MaxBackoff is at least Backoff
+	c = Config{Backoff: time.Minute, MaxBackoff: time.Second}.withDefaults()
+	if got := c.backoff(3); got < 30*time.Second || got > time.Minute {
+		t.Errorf("clamped: %v", got)
+	}
+}
+
+func TestRestartPolicy(t *testing.T) {
+	for p, want := range map[RestartPolicy][2]bool{
+		RestartOnFailure: {false, true},
+		RestartNever:     {false, false},
+		RestartAlways:    {true, true},
+	} {
+		c := Config{Restart: p}
+		if got := c.restart(nil); got != want[0] {
+			t.Errorf("%v: restart after success: %v", p, got)
+		}
+		if got := c.restart(errExit); got != want[1] {
+			t.Errorf("%v: restart after failure: %v", p, got)
+		}
+	}
+}
+
+// testExit is that of the daemon with the given error; it cancels the
+// scheduled restart, if any, and returns the daemon's resulting state.
+func testExit(d *Daemons, dm *daemon, err error) state {
+	d.exited(dm, err)
+	if dm.timer != nil {
+		dm.timer.Stop()
+		dm.timer = nil
+	}
+	return dm.state
+}
+
+func TestCrashLoop(t *testing.T) {
+	d := &Daemons{changed: make(chan struct{})}
+	dm := &daemon{
+		args: []string{"crasher"},
+		config: Config{
+			// long enough that the restarts aren't run
+			Backoff:     time.Hour,
+			CrashLoop:   3,
+			MaxRestarts: 5,
+		}.withDefaults(),
+		started: time.Now(),
+	}
+	for i, want := range []state{
+		backoff,
+		backoff,
+		crashLoop,
+		crashLoop,
+		crashLoop,
+		failed,
+	} {
+		if got := testExit(d, dm, errExit); got != want {
+			t.Fatalf("exit %d: %v, want %v", i+1, got, want)
+		}
+	}
+	if dm.restarts != 5 {
+		t.Errorf("%d restarts, want 5", dm.restarts)
+	}
+
+	// restarts aren't consecutive once it's run for ResetAfter
+	dm.started = time.Now().Add(-2 * dm.config.ResetAfter)
+	if got := testExit(d, dm, errExit); got != backoff || dm.restarts != 1 {
+		t.Errorf("after reset: %v, %d restarts", got, dm.restarts)
+	}
+
+	// a clean exit isn't restarted on failure
+	if got := testExit(d, dm, nil); got != exited {
+		t.Errorf("clean exit: %v", got)
+	}
+
+	// nor are any while stopping
+	dm.config.Restart = RestartAlways
+	d.stopping = true
+	if got := testExit(d, dm, errExit); got != failed {
+		t.Errorf("stopping: %v", got)
+	}
+}
+
+func TestUnlimitedRestarts(t *testing.T) {
+	d := &Daemons{changed: make(chan struct{})}
+	dm := &daemon{
+		args: []string{"always"},
+		config: Config{
+			Restart:     RestartAlways,
+			Backoff:     time.Hour,
+			MaxRestarts: -1,
+		}.withDefaults(),
+		started: time.Now(),
+	}
+	for i := 0; i < 20; i++ {
+		if got := testExit(d, dm, nil); got == failed {
+			t.Fatalf("exit %d: %v", i+1, got)
+		}
+	}
+	if dm.state != crashLoop {
+		t.Errorf("%v, want %v", dm.state, crashLoop)
+	}
+}
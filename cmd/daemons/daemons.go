@@ -22,34 +22,163 @@ import (
 
 const (
 	sockname    = "goes-daemons"
-	maxRestarts = 1
+	logsockname = "goes-daemons-log"
 )
 
 type Daemons struct {
-	mutex sync.Mutex
-	goes  *goes.Goes
-	rpc   *atsock.RpcServer
-	done  chan struct{}
-	pids  []int
-	log   daemonLog
+	mutex   sync.Mutex
+	goes    *goes.Goes
+	rpc     *atsock.RpcServer
+	done    chan struct{}
+	daemons []*daemon
+	config  map[string]Config
+	log     daemonLog
+	// cgroup is the cgroup v2 parent of the daemon groups, if any
+	cgroup string
+	// changed is closed, then replaced, with each change of a daemon's
+	// state for those waiting on its readiness.
+	changed chan struct{}
 
-	cmdsByPid map[int]*exec.Cmd
-	stopping  bool
+	stopping bool
+}
+
+// daemon is a supervised command that keeps its place, and last pid, after
+// it exits so that it may be restarted.
+type daemon struct {
+	args   []string
+	config Config
+	cmd    *exec.Cmd
+	pid    int
+	cgroup string
+	state  state
+	ready  bool
+	// pending are the unready daemons of After and Requires
+	pending []string
+	// restarts are consecutive, without running for ResetAfter
+	restarts int
+	started  time.Time
+	err      error
+	timer    *time.Timer
+	next     time.Time
+	// beat is the time of the last heartbeat that's checked by watch
+	beat  time.Time
+	watch *time.Timer
+	// hung is true if the daemon was killed as unhealthy, which it's
+	// been that many times
+	hung      bool
+	unhealthy int
+	// dropped are the limits of its config that its cgroup doesn't have
+	dropped []string
+}
+
+type state int
+
+const (
+	running state = iota
+	starting
+	backoff
+	crashLoop
+	waiting
+	exited
+	failed
+)
+
+func (s state) String() string {
+	switch s {
+	case running:
+		return "running"
+	case starting:
+		return "starting"
+	case backoff:
+		return "backoff"
+	case crashLoop:
+		return "crash-loop"
+	case waiting:
+		return "waiting"
+	case exited:
+		return "exited"
+	case failed:
+		return "failed"
+	}
+	return "invalid"
+}
+
+func (dm *daemon) status() string {
+	s := dm.state.String()
+	switch dm.state {
+	case backoff, crashLoop:
+		t := time.Until(dm.next).Round(100 * time.Millisecond)
+		s += fmt.Sprint(", restart ", dm.restarts, " in ", t)
+	case waiting:
+		s += " for " + strings.Join(dm.pending, ", ")
+	case failed:
+		if dm.err != nil {
+			s += fmt.Sprint(", ", dm.err)
+		}
+		fallthrough
+	default:
+		if dm.restarts > 0 {
+			s += fmt.Sprint(", restarts ", dm.restarts)
+		}
+	}
+	if dm.unhealthy > 0 {
+		s += fmt.Sprint(", unhealthy ", dm.unhealthy)
+	}
+	if len(dm.dropped) > 0 {
+		s += ", without " + strings.Join(dm.dropped, ", ")
+	}
+	if dm.cmd != nil && len(dm.cgroup) > 0 {
+		cpu, rss := cgroupUsage(dm.cgroup)
+		if len(cpu) > 0 {
+			s += ", cpu " + cpu
+		}
+		if len(rss) > 0 {
+			s += ", rss " + rss
+		}
+	}
+	return s
 }
 
 func (d *Daemons) init() {
 	d.done = make(chan struct{})
-	d.cmdsByPid = make(map[int]*exec.Cmd)
+	d.changed = make(chan struct{})
+	d.cgroup = cgroupInit()
 	d.log.init()
 	log.Tee(&d.log)
 
 }
 
-func (d *Daemons) start(restarts int, args ...string) {
+func (d *Daemons) start(args ...string) {
+	dm := &daemon{
+		args:   args,
+		config: d.config[args[0]].withDefaults(),
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	i := len(d.daemons)
+	for j, x := range d.daemons {
+		// a failed or exited daemon is started anew in its place
+		if x.state >= exited && equal(x.args, args) {
+			i = j
+			break
+		}
+	}
+	if i < len(d.daemons) {
+		d.daemons[i] = dm
+	} else {
+		d.daemons = append(d.daemons, dm)
+	}
+	d.launch(dm)
+}
+
+// run the daemon's command; the caller must hold the mutex.
+func (d *Daemons) run(dm *daemon) {
+	args := dm.args
 	rout, wout, err := os.Pipe()
 	defer func(cs string) {
 		if err != nil {
 			log.Print("daemon", "err", cs, ": ", err)
+			d.exited(dm, err)
 		}
 	}(strings.Join(args, " "))
 	if err != nil {
@@ -57,8 +186,35 @@ func (d *Daemons) start(restarts int, args ...string) {
 	}
 	rerr, werr, err := os.Pipe()
 	if err != nil {
+		rout.Close()
+		wout.Close()
 		return
 	}
+	var (
+		cgroup  *os.File
+		dropped []cgroupLimit
+	)
+	dm.cgroup, dm.dropped = "", nil
+	if len(d.cgroup) > 0 {
+		dm.cgroup, cgroup, dropped, err = cgroupCreate(d.cgroup,
+			dm.config, args[0])
+		if err != nil {
+			log.Print("daemon", "err", args, ": cgroup: ", err)
+			err = nil
+		}
+	}
+	if len(dm.cgroup) == 0 {
+		dropped = cgroupLimits(dm.config)
+		for i := range dropped {
+			dropped[i].err = fmt.Errorf("%s: %d: no cgroup",
+				dropped[i].fn, dropped[i].v)
+		}
+	}
+	// it runs without the limits that it can't have
+	for _, limit := range dropped {
+		log.Print("daemon", "err", args, ": dropped ", limit.err)
+		dm.dropped = append(dm.dropped, limit.fn)
+	}
 	p := d.goes.Fork(args...)
 	p.Stdin = nil
 	p.Stdout = wout
@@ -68,46 +224,145 @@ func (d *Daemons) start(restarts int, args ...string) {
 		"PATH=" + prog.Path(),
 		"TERM=linux",
 	}
-	if err = p.Start(); err != nil {
+	if len(dm.config.Rlimit) > 0 {
+		p.Env = append(p.Env, rlimitEnv+"="+rlimits(dm.config.Rlimit))
+	}
+	if cgroup != nil {
+		// start it in the group so it, and what it forks, can't
+		// escape its limits
+		p.SysProcAttr = &syscall.SysProcAttr{
+			UseCgroupFD: true,
+			CgroupFD:    int(cgroup.Fd()),
+		}
+	}
+	err = p.Start()
+	if cgroup != nil {
+		cgroup.Close()
+	}
+	if err != nil {
+		for _, f := range []*os.File{rout, wout, rerr, werr} {
+			f.Close()
+		}
+		if len(dm.cgroup) > 0 {
+			os.Remove(dm.cgroup)
+			dm.cgroup = ""
+		}
 		return
 	}
 	log.Print("daemon", "info", "running ", p.Process.Pid, " ", args)
 	id := fmt.Sprintf("%s.%s[%d]", prog.Base(), args[0], p.Process.Pid)
-	d.mutex.Lock()
-	d.pids = append(d.pids, p.Process.Pid)
-	d.cmdsByPid[p.Process.Pid] = p
-	d.mutex.Unlock()
+	dm.cmd = p
+	dm.pid = p.Process.Pid
+	dm.ready = !dm.config.Notify
+	if dm.ready {
+		dm.state = running
+	} else {
+		dm.state = starting
+	}
+	dm.started = time.Now()
+	dm.err = nil
+	d.broadcast()
+	if dm.config.Liveness > 0 {
+		dm.beat = dm.started
+		dm.watch = time.AfterFunc(dm.config.Liveness,
+			func() { d.check(dm, p) })
+	}
 	go log.LinesFrom(rout, id, "info")
 	go log.LinesFrom(rerr, id, "err")
-	go func(p *exec.Cmd, wout, werr *os.File, args ...string) {
-		if err := p.Wait(); err != nil {
+	go func(p *exec.Cmd, wout, werr *os.File, cgroup string) {
+		err := p.Wait()
+		if err != nil {
 			fmt.Fprintln(werr, err)
 		} else {
 			fmt.Fprintln(wout, "done")
 		}
-		if d.cmd(p.Process.Pid) != nil {
-			d.del(p.Process.Pid)
-			if restarts == maxRestarts {
-				fmt.Fprintln(werr, "to many restarts")
-			} else {
-				fmt.Fprintln(werr, "restart")
-				defer d.start(restarts+1, args...)
+		d.mutex.Lock()
+		// unless stopped
+		if dm.cmd == p {
+			if note := d.exited(dm, err); len(note) > 0 {
+				fmt.Fprintln(werr, note)
 			}
 		}
+		d.mutex.Unlock()
+		if len(cgroup) > 0 {
+			// fails if any of its children remain
+			os.Remove(cgroup)
+		}
 		wout.Sync()
 		werr.Sync()
 		wout.Close()
 		werr.Close()
-	}(p, wout, werr, args...)
+	}(p, wout, werr, dm.cgroup)
+}
+
+// exited updates the state of the daemon after its exit, or failure to
+// start, and schedules any restart by its policy; the caller must hold the
+// mutex. It returns a note for the daemon's log.
+func (d *Daemons) exited(dm *daemon, err error) string {
+	defer d.broadcast()
+	dm.cmd = nil
+	dm.err = err
+	dm.ready = false
+	if dm.watch != nil {
+		dm.watch.Stop()
+		dm.watch = nil
+	}
+	if dm.hung {
+		dm.hung = false
+		dm.err = fmt.Errorf("no heartbeat in %v", dm.config.Liveness)
+		err = dm.err
+	}
+	if !dm.started.IsZero() && time.Since(dm.started) >= dm.config.ResetAfter {
+		dm.restarts = 0
+	}
+	switch {
+	case d.stopping || !dm.config.restart(err):
+		if err != nil {
+			dm.state = failed
+		} else {
+			dm.state = exited
+		}
+		return ""
+	case dm.config.MaxRestarts > 0 && dm.restarts >= dm.config.MaxRestarts:
+		dm.state = failed
+		log.Print("daemon", "err", "failed after ", dm.restarts,
+			" restarts: ", dm.args)
+		return "too many restarts"
+	}
+	t := dm.config.backoff(dm.restarts)
+	dm.restarts++
+	if dm.restarts < dm.config.CrashLoop {
+		dm.state = backoff
+	} else {
+		if dm.state != crashLoop {
+			log.Print("daemon", "err", "crash-loop: ", dm.args)
+		}
+		dm.state = crashLoop
+	}
+	dm.next = time.Now().Add(t)
+	dm.timer = time.AfterFunc(t, func() { d.respawn(dm) })
+	return fmt.Sprint("restart in ", t.Round(time.Millisecond))
+}
+
+// respawn runs the daemon after its backoff unless it has since been
+// stopped or restarted by admin.
+func (d *Daemons) respawn(dm *daemon) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.stopping || dm.timer == nil || d.index(dm) < 0 {
+		return
+	}
+	dm.timer = nil
+	log.Print("daemon", "info", "restarting: ", dm.args)
+	d.launch(dm)
 }
 
 func (d *Daemons) List(args struct{}, reply *string) error {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 	buf := &bytes.Buffer{}
-	for _, pid := range d.pids {
-		p := d.cmdsByPid[pid]
-		fmt.Fprintf(buf, "%d: %v\n", pid, p.Args)
+	for _, dm := range d.daemons {
+		fmt.Fprintf(buf, "%d: %v %s\n", dm.pid, dm.args, dm.status())
 	}
 	*reply = buf.String()
 	return nil
@@ -126,7 +381,7 @@ func (d *Daemons) Log(args []string, reply *string) error {
 }
 
 func (d *Daemons) Start(args []string, reply *struct{}) error {
-	d.start(0, args...)
+	d.start(args...)
 	return nil
 }
 
@@ -141,84 +396,118 @@ func (d *Daemons) Stop(pids []int, reply *struct{}) error {
 		log.Print("daemon", "info", "stopping")
 		defer close(d.done)
 		// stop all in reverse order
-		pids = make([]int, len(d.pids))
-		for i, pid := range d.pids {
-			pids[len(pids)-i-1] = pid
+		pids = d.pids()
+		for i, j := 0, len(pids)-1; i < j; i, j = i+1, j-1 {
+			pids[i], pids[j] = pids[j], pids[i]
 		}
 		d.mutex.Unlock()
 	}
-	return d.stop(pids)
+	return d.stopInOrder(pids)
 }
 
+// Restart stops, then starts anew, the given or else all daemons, including
+// those that failed or are waiting to restart.
 func (d *Daemons) Restart(pids []int, reply *struct{}) error {
 	var pargs [][]string
 	d.mutex.Lock()
 	if len(pids) == 0 {
-		// stop all in reverse order
-		pids = make([]int, len(d.pids))
-		for i, pid := range d.pids {
-			pids[len(pids)-i-1] = pid
-		}
-		// but restart in original order
-		pargs = make([][]string, len(pids))
-		for i, pid := range d.pids {
-			p := d.cmdsByPid[pid]
-			pargs[i] = make([]string, len(p.Args))
-			copy(pargs[i], p.Args)
-		}
-	} else {
-		pargs = make([][]string, len(pids))
-		for i, pid := range pids {
-			p := d.cmdsByPid[pid]
-			pargs[i] = make([]string, len(p.Args))
-			copy(pargs[i], p.Args)
+		pids = d.pids()
+	}
+	for _, pid := range pids {
+		if dm := d.find(pid); dm != nil {
+			pargs = append(pargs, append([]string{}, dm.args...))
 		}
 	}
 	d.mutex.Unlock()
-	if err := d.stop(pids); err != nil {
+	// stop in reverse order
+	rpids := make([]int, len(pids))
+	for i, pid := range pids {
+		rpids[len(pids)-i-1] = pid
+	}
+	if err := d.stopInOrder(rpids); err != nil {
 		return err
 	}
+	// but restart in original order, with each waiting on its After and
+	// Requires
 	for _, args := range pargs {
 		log.Print("daemon", "info", "restarting: ", args)
-		d.start(0, args...)
+		d.start(args...)
 	}
 	return nil
 }
 
-func (d *Daemons) cmd(pid int) *exec.Cmd {
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-	return d.cmdsByPid[pid]
+// pids returns those of the daemons in the order started; the caller must
+// hold the mutex.
+func (d *Daemons) pids() []int {
+	pids := make([]int, len(d.daemons))
+	for i, dm := range d.daemons {
+		pids[i] = dm.pid
+	}
+	return pids
 }
 
-func (d *Daemons) del(pid int) {
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-	delete(d.cmdsByPid, pid)
-	for i, entry := range d.pids {
-		if pid == entry {
-			n := copy(d.pids[i:], d.pids[i+1:])
-			d.pids = d.pids[:i+n]
-			break
+// find returns the daemon with the pid, or its last pid if not running;
+// the caller must hold the mutex.
+func (d *Daemons) find(pid int) *daemon {
+	for _, dm := range d.daemons {
+		if dm.pid == pid {
+			return dm
 		}
 	}
+	return nil
+}
+
+func (d *Daemons) index(dm *daemon) int {
+	for i, x := range d.daemons {
+		if x == dm {
+			return i
+		}
+	}
+	return -1
+}
+
+// del removes the daemon and cancels any pending restart; it returns the
+// command that's still running, if any. The caller must hold the mutex.
+func (d *Daemons) del(dm *daemon) *exec.Cmd {
+	if i := d.index(dm); i >= 0 {
+		d.daemons = append(d.daemons[:i], d.daemons[i+1:]...)
+	}
+	if dm.timer != nil {
+		dm.timer.Stop()
+		dm.timer = nil
+	}
+	if dm.watch != nil {
+		dm.watch.Stop()
+		dm.watch = nil
+	}
+	p := dm.cmd
+	dm.cmd = nil
+	d.broadcast()
+	return p
 }
 
 func (d *Daemons) stop(pids []int) error {
+	var running []int
 	for _, pid := range pids {
-		if p := d.cmd(pid); p != nil {
-			log.Print("daemon", "info", "stopping: ", p.Args)
-			d.del(pid)
-			p.Process.Signal(syscall.SIGTERM)
-		} else {
+		d.mutex.Lock()
+		dm := d.find(pid)
+		if dm == nil {
+			d.mutex.Unlock()
 			return fmt.Errorf("%d: not found", pid)
 		}
+		log.Print("daemon", "info", "stopping: ", dm.args)
+		p := d.del(dm)
+		d.mutex.Unlock()
+		if p != nil {
+			p.Process.Signal(syscall.SIGTERM)
+			running = append(running, pid)
+		}
 	}
 	have := func(dn string) bool {
 		_, err := os.Stat(dn)
 		return err == nil
 	}
-	for _, pid := range pids {
+	for _, pid := range running {
 		procdn := fmt.Sprint("/proc/", pid)
 		for t := 100 * time.Millisecond; have(procdn); t *= 2 {
 			if t > 3*time.Second {
@@ -231,3 +520,15 @@ func (d *Daemons) stop(pids []int) error {
 	}
 	return nil
 }
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,217 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package daemons
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/platinasystems/atsock"
+	"github.com/platinasystems/log"
+)
+
+// Ready notifies goes-daemons that the calling daemon, configured to Notify,
+// is ready for those that start After or Requires it. It's an error if the
+// caller isn't run by goes-daemons.
+func Ready() error {
+	cl, err := atsock.NewRpcClient(sockname)
+	if err != nil {
+		return err
+	}
+	defer cl.Close()
+	return cl.Call("Daemons.Ready", os.Getpid(), &empty)
+}
+
+// Ready is the notice of the daemon with the given pid that it's ready.
+func (d *Daemons) Ready(pid int, reply *struct{}) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	dm := d.find(pid)
+	if dm == nil || dm.cmd == nil {
+		return fmt.Errorf("%d: not found", pid)
+	}
+	if !dm.ready {
+		log.Print("daemon", "info", "ready: ", dm.args)
+		dm.ready = true
+		dm.state = running
+		dm.beat = time.Now()
+		d.broadcast()
+	}
+	return nil
+}
+
+// broadcast a change of state to those waiting on readiness; the caller
+// must hold the mutex.
+func (d *Daemons) broadcast() {
+	close(d.changed)
+	d.changed = make(chan struct{})
+}
+
+// launch runs the daemon now, or once the daemons that it's After or
+// Requires are ready; the caller must hold the mutex.
+func (d *Daemons) launch(dm *daemon) {
+	if len(dm.config.After) == 0 && len(dm.config.Requires) == 0 {
+		d.run(dm)
+		return
+	}
+	dm.state = waiting
+	dm.pending = nil
+	go d.await(dm)
+}
+
+// await runs the daemon once the daemons that it's After or Requires are
+// ready; or, leaves it failed if any that it Requires fail or aren't ready
+// within its ReadyTimeout.
+func (d *Daemons) await(dm *daemon) {
+	timeout := time.NewTimer(dm.config.ReadyTimeout)
+	defer timeout.Stop()
+	expired := false
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for !d.stopping && dm.state == waiting && d.index(dm) >= 0 {
+		var (
+			pending []string
+			err     error
+		)
+		for _, name := range dm.config.Requires {
+			ready, xerr := d.ready(name)
+			switch {
+			case xerr != nil:
+				err = xerr
+			case !ready && expired:
+				err = fmt.Errorf("%s: not ready", name)
+			case !ready:
+				pending = append(pending, name)
+			}
+			if err != nil {
+				break
+			}
+		}
+		if err != nil {
+			log.Print("daemon", "err", dm.args, ": ", err)
+			dm.state = failed
+			dm.err = err
+			dm.pending = nil
+			d.broadcast()
+			return
+		}
+		for _, name := range dm.config.After {
+			ready, xerr := d.ready(name)
+			if !ready && xerr == nil && !expired {
+				pending = append(pending, name)
+			}
+		}
+		if len(pending) == 0 {
+			dm.pending = nil
+			d.run(dm)
+			return
+		}
+		dm.pending = pending
+		changed := d.changed
+		d.mutex.Unlock()
+		select {
+		case <-changed:
+		case <-timeout.C:
+			expired = true
+		}
+		d.mutex.Lock()
+	}
+}
+
+// ready returns true if a daemon of the given name is ready or has exited
+// successfully; or, an error if all of those have failed. The caller must
+// hold the mutex.
+func (d *Daemons) ready(name string) (bool, error) {
+	var (
+		pending bool
+		err     error
+	)
+	for _, dm := range d.daemons {
+		if dm.args[0] != name {
+			continue
+		}
+		switch {
+		case dm.ready, dm.state == exited:
+			return true, nil
+		case dm.state == failed:
+			err = fmt.Errorf("%s: failed", name)
+		default:
+			// starting, waiting, or restarting
+			pending = true
+		}
+	}
+	if pending {
+		return false, nil
+	}
+	return false, err
+}
+
+// depends returns true if the daemon is After or Requires the named daemon.
+func (dm *daemon) depends(name string) bool {
+	for _, names := range [][]string{dm.config.After, dm.config.Requires} {
+		for _, s := range names {
+			if s == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// stopInOrder stops the daemons in waves, each before any that it's After
+// or Requires.
+func (d *Daemons) stopInOrder(pids []int) error {
+	d.mutex.Lock()
+	waves := d.waves(pids)
+	d.mutex.Unlock()
+	for _, wave := range waves {
+		if err := d.stop(wave); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waves groups the pids by the depth of their dependents among them so that
+// the first has those without any; each retains the given order. The caller
+// must hold the mutex.
+func (d *Daemons) waves(pids []int) [][]int {
+	dms := make([]*daemon, len(pids))
+	for i, pid := range pids {
+		dms[i] = d.find(pid)
+	}
+	depth := make(map[*daemon]int)
+	var depthOf func(dm *daemon) int
+	depthOf = func(dm *daemon) int {
+		if n, found := depth[dm]; found {
+			return n
+		}
+		// this breaks a cycle of dependencies
+		depth[dm] = 0
+		n := 0
+		for _, x := range dms {
+			if x != nil && x != dm && x.depends(dm.args[0]) {
+				if m := depthOf(x) + 1; m > n {
+					n = m
+				}
+			}
+		}
+		depth[dm] = n
+		return n
+	}
+	var waves [][]int
+	for i, pid := range pids {
+		n := 0
+		if dms[i] != nil {
+			n = depthOf(dms[i])
+		}
+		for len(waves) <= n {
+			waves = append(waves, nil)
+		}
+		waves[n] = append(waves[n], pid)
+	}
+	return waves
+}
@@ -0,0 +1,98 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package daemons
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// supervise returns Daemons of the named commands, with pids 1, 2, etc., in
+// order; each After those that follow its ':', e.g. "web:db,cache".
+func supervise(specs ...string) *Daemons {
+	d := &Daemons{changed: make(chan struct{})}
+	for i, spec := range specs {
+		name := strings.Split(spec, ":")
+		dm := &daemon{args: name[:1], pid: i + 1}
+		if len(name) > 1 {
+			dm.config.After = strings.Split(name[1], ",")
+		}
+		d.daemons = append(d.daemons, dm)
+	}
+	return d
+}
+
+func TestWaves(t *testing.T) {
+	d := supervise("db", "cache", "web:db,cache", "proxy:web", "cron")
+	// proxy, and the independent cron, stop first; then web; then db and
+	// cache that web is After
+	want := [][]int{{4, 5}, {3}, {1, 2}}
+	if got := d.waves([]int{1, 2, 3, 4, 5}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// only the dependents that are also stopped
+	want = [][]int{{3}, {1}}
+	if got := d.waves([]int{1, 3}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// Requires orders the same as After
+	d.daemons[2].config.Requires = d.daemons[2].config.After
+	d.daemons[2].config.After = nil
+	want = [][]int{{4}, {3}, {2}}
+	if got := d.waves([]int{2, 3, 4}); !reflect.DeepEqual(got, want) {
+		t.Errorf("requires: got %v, want %v", got, want)
+	}
+}
+
+func TestWavesCycle(t *testing.T) {
+	// web and cache are After each other and db is After both
+	d := supervise("web:cache", "cache:web", "db:web,cache", "self:self")
+	waves := d.waves([]int{1, 2, 3, 4})
+	wave := make(map[int]int)
+	for i, pids := range waves {
+		for _, pid := range pids {
+			if _, dup := wave[pid]; dup {
+				t.Fatalf("%v: %d is stopped twice", waves, pid)
+			}
+			wave[pid] = i
+		}
+	}
+	if len(wave) != 4 {
+		t.Fatalf("%v: not all stopped", waves)
+	}
+	// the cycle doesn't stop db before either of those that it's After
+	if wave[3] >= wave[1] || wave[3] >= wave[2] {
+		t.Errorf("%v: db isn't first", waves)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	d := supervise("db", "db", "web")
+	for _, step := range []struct {
+		states  [3]state
+		ready   [3]bool
+		want    bool
+		wantErr bool
+	}{
+		// waiting on either instance
+		{[3]state{starting, backoff, running}, [3]bool{}, false, false},
+		// one of those that's ready, or exited, is enough
+		{[3]state{running, failed, running}, [3]bool{true}, true, false},
+		{[3]state{exited, failed, running}, [3]bool{}, true, false},
+		// not unless all have failed
+		{[3]state{failed, crashLoop, running}, [3]bool{}, false, false},
+		{[3]state{failed, failed, running}, [3]bool{}, false, true},
+	} {
+		for i, dm := range d.daemons {
+			dm.state, dm.ready = step.states[i], step.ready[i]
+		}
+		ready, err := d.ready("db")
+		if ready != step.want || (err != nil) != step.wantErr {
+			t.Errorf("%v: ready %v, err %v", step.states[:2], ready,
+				err)
+		}
+	}
+}
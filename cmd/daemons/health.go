@@ -0,0 +1,106 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package daemons
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/platinasystems/atsock"
+	"github.com/platinasystems/log"
+)
+
+// Heartbeat notifies goes-daemons that the calling daemon is alive. A daemon
+// configured with Liveness should call this periodically, e.g. each half of
+// that, from the loop that would otherwise wedge.
+func Heartbeat() error {
+	cl, err := atsock.NewRpcClient(sockname)
+	if err != nil {
+		return err
+	}
+	defer cl.Close()
+	return cl.Call("Daemons.Heartbeat", os.Getpid(), &empty)
+}
+
+// Healthy returns an error that lists the Critical daemons that are
+// unhealthy, i.e. without a recent heartbeat, in crash-loop, or failed; or,
+// if goes-daemons isn't running. Those that exited without error under a
+// policy that doesn't restart them are healthy. A machine may tie this to
+// its watchdog.
+func Healthy() error {
+	var s string
+	cl, err := atsock.NewRpcClient(sockname)
+	if err != nil {
+		return err
+	}
+	defer cl.Close()
+	if err = cl.Call("Daemons.Health", struct{}{}, &s); err != nil {
+		return err
+	}
+	if len(s) > 0 {
+		return errors.New(strings.TrimSpace(s))
+	}
+	return nil
+}
+
+// Heartbeat is the notice of the daemon with the given pid that it's alive.
+func (d *Daemons) Heartbeat(pid int, reply *struct{}) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	dm := d.find(pid)
+	if dm == nil || dm.cmd == nil {
+		return fmt.Errorf("%d: not found", pid)
+	}
+	dm.beat = time.Now()
+	return nil
+}
+
+// Health replies with a line for each unhealthy Critical daemon.
+func (d *Daemons) Health(args struct{}, reply *string) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	buf := &bytes.Buffer{}
+	for _, dm := range d.daemons {
+		if !dm.config.Critical || d.stopping {
+			continue
+		}
+		switch {
+		case dm.state == crashLoop, dm.state == failed, dm.hung:
+			fmt.Fprintf(buf, "%v: %s\n", dm.args, dm.status())
+		case dm.config.Liveness > 0 && dm.cmd != nil &&
+			time.Since(dm.beat) > dm.config.Liveness:
+			fmt.Fprintf(buf, "%v: no heartbeat\n", dm.args)
+		}
+	}
+	*reply = buf.String()
+	return nil
+}
+
+// check the liveness of the daemon's command and kill it if it's missed its
+// heartbeat; otherwise, check again when it may.
+func (d *Daemons) check(dm *daemon, p *exec.Cmd) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if dm.cmd != p || dm.watch == nil {
+		return
+	}
+	since := time.Since(dm.beat)
+	if since < dm.config.Liveness {
+		dm.watch.Reset(dm.config.Liveness - since)
+		return
+	}
+	log.Print("daemon", "err", "unhealthy, no heartbeat in ",
+		since.Round(time.Millisecond), ": ", dm.args)
+	dm.hung = true
+	dm.unhealthy++
+	// it's wedged, so TERM may be ignored
+	p.Process.Signal(syscall.SIGKILL)
+}
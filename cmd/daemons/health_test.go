@@ -0,0 +1,108 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package daemons
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// health returns the reply of the Health RPC.
+func health(t *testing.T, d *Daemons) string {
+	t.Helper()
+	var s string
+	if err := d.Health(struct{}{}, &s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestHeartbeat(t *testing.T) {
+	d := supervise("critical", "other")
+	for _, dm := range d.daemons {
+		dm.cmd = &exec.Cmd{}
+		dm.config.Liveness = 50 * time.Millisecond
+		dm.beat = time.Now().Add(-time.Second)
+	}
+	d.daemons[0].config.Critical = true
+	if s := health(t, d); s != "[critical]: no heartbeat\n" {
+		t.Errorf("stale: %q", s)
+	}
+	if err := d.Heartbeat(1, &empty); err != nil {
+		t.Fatal(err)
+	}
+	if s := health(t, d); len(s) > 0 {
+		t.Errorf("after heartbeat: %q", s)
+	}
+	time.Sleep(2 * d.daemons[0].config.Liveness)
+	if s := health(t, d); !strings.Contains(s, "no heartbeat") {
+		t.Errorf("stale again: %q", s)
+	}
+	if err := d.Heartbeat(3, &empty); err == nil {
+		t.Error("heartbeat of an unknown pid")
+	}
+	// those that aren't running don't beat
+	d.daemons[0].cmd = nil
+	d.daemons[0].state = backoff
+	if s := health(t, d); len(s) > 0 {
+		t.Errorf("restarting: %q", s)
+	}
+}
+
+func TestHealthStates(t *testing.T) {
+	healthy := map[state]bool{
+		running:   true,
+		starting:  true,
+		backoff:   true,
+		waiting:   true,
+		crashLoop: false,
+		// without error under a policy that doesn't restart it
+		exited: true,
+		failed: false,
+	}
+	for st, want := range healthy {
+		d := supervise("x")
+		d.daemons[0].state = st
+		d.daemons[0].config.Critical = true
+		if got := len(health(t, d)) == 0; got != want {
+			t.Errorf("%v: healthy %v", st, got)
+		}
+		// none are unhealthy while stopping
+		d.stopping = true
+		if s := health(t, d); len(s) > 0 {
+			t.Errorf("%v while stopping: %q", st, s)
+		}
+	}
+}
+
+func TestHung(t *testing.T) {
+	d := supervise("wedged")
+	dm := d.daemons[0]
+	dm.config = Config{
+		Backoff:  time.Hour,
+		Liveness: time.Second,
+		Critical: true,
+	}.withDefaults()
+	dm.state = running
+	dm.started = time.Now()
+	// as killed by check
+	dm.hung = true
+	if s := health(t, d); !strings.HasPrefix(s, "[wedged]: running") {
+		t.Errorf("hung: %q", s)
+	}
+	// it's restarted by policy with the reason that it was killed
+	d.exited(dm, &exec.ExitError{})
+	if dm.timer != nil {
+		dm.timer.Stop()
+	}
+	if dm.hung || dm.state != backoff {
+		t.Errorf("after exit: hung %v, %v", dm.hung, dm.state)
+	}
+	if dm.err == nil || dm.err.Error() != "no heartbeat in 1s" {
+		t.Errorf("err %v", dm.err)
+	}
+}
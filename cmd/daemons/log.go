@@ -5,73 +5,423 @@
 package daemons
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
+	"encoding/gob"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"log/syslog"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/platinasystems/goes/internal/prog"
+	"github.com/platinasystems/log"
 )
 
 const (
 	logEntries = 128
-	logCap     = 160
+	// each follower buffers this many entries, then skips the rest
+	logFollow = 256
+	// logStamp is that of the persistent log files
+	logStamp = "2006-01-02T15:04:05.000000Z07:00"
 )
 
 type logEntry struct {
-	sync.Mutex
-	t time.Time
-	b []byte
+	t    time.Time
+	pri  syslog.Priority
+	name string
+	id   string
+	msg  string
 }
 
+// daemonLog has the recent log entries of goes-daemons and its daemons, by
+// name, in a ring and, with dir, persistent files, NAME.log, that are
+// rotated to gzip compressed NAME.log.1.gz, etc., when larger than size.
 type daemonLog struct {
-	r []logEntry
-	i int
+	mutex sync.Mutex
+	r     []logEntry
+	i     int
+
+	dir  string
+	size int64
+	keep int
+	// files and their sizes by daemon name
+	files map[string]*logFile
+	// rotating is held while compressing, or reading, the full files,
+	// apart from the mutex so that logging doesn't wait
+	rotating sync.Mutex
+
+	followers map[chan logEntry]struct{}
+}
+
+type logFile struct {
+	*os.File
+	size int64
+}
+
+// logQuery is the request of the log stream with, if non-zero, the
+// tail N entries; those since the given time; or, those of the given
+// priority or more severe.
+type logQuery struct {
+	Follow   bool
+	N        int
+	Daemon   string
+	Since    time.Time
+	Priority int
 }
 
 func (dl *daemonLog) init() {
 	dl.r = make([]logEntry, logEntries)
-	for i := range dl.r {
-		dl.r[i].b = make([]byte, 0, logCap)
+	dl.files = make(map[string]*logFile)
+	dl.followers = make(map[chan logEntry]struct{})
+	if len(dl.dir) > 0 {
+		if err := os.MkdirAll(dl.dir, 0755); err != nil {
+			fmt.Fprintln(os.Stderr, "goes-daemons:", err)
+			dl.dir = ""
+		}
+	}
+	if dl.size <= 0 {
+		dl.size = 256 << 10
+	}
+	if dl.keep <= 0 {
+		dl.keep = 4
 	}
 }
 
 func (dl *daemonLog) String() string {
 	buf := new(bytes.Buffer)
-	cat := func(l *logEntry) {
-		fmt.Fprint(buf, l.t.Format(time.Stamp), " ")
-		buf.Write(l.b)
-	}
-	for i := dl.i; i < len(dl.r); i++ {
-		l := &dl.r[i]
-		if l.t.IsZero() || len(l.b) == 0 {
-			break
-		}
-		cat(l)
-	}
-	for i := 0; i < dl.i; i++ {
-		cat(&dl.r[i])
+	q := logQuery{Priority: int(syslog.LOG_DEBUG)}
+	for _, l := range dl.recent(q) {
+		l.format(buf)
 	}
 	return buf.String()
 }
 
+// Write the "<PRI>ID: MSG\n" lines teed from log.
 func (dl *daemonLog) Write(b []byte) (int, error) {
-	const ellipsis = "...\n"
-	l := &dl.r[dl.i]
-	l.t = time.Now()
-	if len(b) > 4 && b[0] == '<' && b[3] == '>' {
-		// skip log priority prefix
-		b = b[4:]
-	}
-	if len(b) > cap(l.b) {
-		l.b = l.b[:cap(l.b)]
-		n := cap(l.b) - len(ellipsis)
-		copy(l.b, b[:n])
-		copy(l.b[n:], ellipsis)
-	} else {
-		l.b = l.b[:len(b)]
-		copy(l.b, b)
+	l := logEntry{
+		t:   time.Now(),
+		pri: syslog.LOG_INFO,
+	}
+	s := strings.TrimRight(string(b), "\n")
+	if strings.HasPrefix(s, "<") {
+		if i := strings.IndexByte(s, '>'); i > 0 {
+			if u, err := strconv.ParseUint(s[1:i], 10, 0); err == nil {
+				l.pri = syslog.Priority(u) & log.PriorityMask
+				s = s[i+1:]
+			}
+		}
+	}
+	l.id, l.msg = s, ""
+	if i := strings.Index(s, ": "); i > 0 {
+		l.id, l.msg = s[:i], s[i+2:]
 	}
+	l.name = logName(l.id)
+	dl.mutex.Lock()
+	defer dl.mutex.Unlock()
+	dl.r[dl.i] = l
 	if dl.i++; dl.i >= logEntries {
 		dl.i = 0
 	}
+	if len(dl.dir) > 0 {
+		dl.persist(&l)
+	}
+	for c := range dl.followers {
+		select {
+		case c <- l:
+		default:
+		}
+	}
 	return len(b), nil
 }
+
+// logName returns the daemon name of the log ID, PROG.NAME[PID], or that of
+// goes-daemons, e.g. goes.goes-daemons[PID].
+func logName(id string) string {
+	if i := strings.LastIndexByte(id, '['); i > 0 {
+		id = id[:i]
+	}
+	if strings.HasPrefix(id, prog.Base()+".") {
+		id = id[len(prog.Base())+1:]
+	}
+	return filepath.Base(id)
+}
+
+func (l *logEntry) format(w io.Writer) {
+	fmt.Fprint(w, l.t.Format(time.Stamp), " ", l.id, ": ", l.msg, "\n")
+}
+
+func (l *logEntry) match(q logQuery) bool {
+	return (len(q.Daemon) == 0 || l.name == q.Daemon) &&
+		!l.t.Before(q.Since) && int(l.pri) <= q.Priority
+}
+
+// persist the entry to the daemon's file, rotating it if it's full; the
+// caller must hold the mutex.
+func (dl *daemonLog) persist(l *logEntry) {
+	f := dl.files[l.name]
+	if f == nil {
+		fn := filepath.Join(dl.dir, l.name+".log")
+		x, err := os.OpenFile(fn, os.O_WRONLY|os.O_CREATE|os.O_APPEND,
+			0644)
+		if err != nil {
+			return
+		}
+		f = &logFile{File: x}
+		if fi, err := x.Stat(); err == nil {
+			f.size = fi.Size()
+		}
+		dl.files[l.name] = f
+	}
+	n, _ := fmt.Fprint(f, l.t.Format(logStamp), " ",
+		log.LogPriorityByValue[l.pri], " ", l.id, ": ", l.msg, "\n")
+	if f.size += int64(n); f.size >= dl.size {
+		f.Close()
+		delete(dl.files, l.name)
+		fn := filepath.Join(dl.dir, l.name+".log")
+		// to compress, outside of the mutex, as FILE.1.gz
+		if os.Rename(fn, fmt.Sprint(fn, ".", time.Now().UnixNano())) ==
+			nil {
+			go dl.rotate(fn)
+		}
+	}
+}
+
+// rotate the full log files, FILE.STAMP, in order to FILE.1.gz after
+// FILE.2.gz, etc.
+func (dl *daemonLog) rotate(fn string) {
+	dl.rotating.Lock()
+	defer dl.rotating.Unlock()
+	for _, full := range stamped(fn) {
+		os.Remove(fmt.Sprint(fn, ".", dl.keep, ".gz"))
+		for i := dl.keep - 1; i > 0; i-- {
+			os.Rename(fmt.Sprint(fn, ".", i, ".gz"),
+				fmt.Sprint(fn, ".", i+1, ".gz"))
+		}
+		buf, err := ioutil.ReadFile(full)
+		if err != nil {
+			continue
+		}
+		f, err := os.Create(fn + ".1.gz")
+		if err != nil {
+			continue
+		}
+		w := gzip.NewWriter(f)
+		w.Write(buf)
+		w.Close()
+		f.Close()
+		os.Remove(full)
+	}
+}
+
+// stamped returns the full log files, FILE.STAMP, that are yet to be
+// compressed, oldest first.
+func stamped(fn string) []string {
+	var full []string
+	matches, _ := filepath.Glob(fn + ".*")
+	for _, match := range matches {
+		stamp := strings.TrimPrefix(match, fn+".")
+		if _, err := strconv.ParseInt(stamp, 10, 64); err == nil {
+			full = append(full, match)
+		}
+	}
+	sort.Strings(full)
+	return full
+}
+
+// recent returns the ring's entries that match the query.
+func (dl *daemonLog) recent(q logQuery) []logEntry {
+	dl.mutex.Lock()
+	defer dl.mutex.Unlock()
+	var entries []logEntry
+	for _, r := range [][]logEntry{dl.r[dl.i:], dl.r[:dl.i]} {
+		for _, l := range r {
+			if !l.t.IsZero() && l.match(q) {
+				entries = append(entries, l)
+			}
+		}
+	}
+	return tail(entries, q.N)
+}
+
+// history returns the entries of the persistent files that match the query;
+// unless it's for the tail N entries or since a time, that's just those of
+// the current files and not the full or rotated ones.
+func (dl *daemonLog) history(q logQuery) []logEntry {
+	// so that none of the full files are read both before and after
+	// they're compressed
+	dl.rotating.Lock()
+	defer dl.rotating.Unlock()
+	names := []string{q.Daemon}
+	if len(q.Daemon) == 0 {
+		fns, _ := filepath.Glob(filepath.Join(dl.dir, "*.log"))
+		names = names[:0]
+		for _, fn := range fns {
+			names = append(names, strings.TrimSuffix(filepath.Base(fn),
+				".log"))
+		}
+	}
+	var entries []logEntry
+	for _, name := range names {
+		var these []logEntry
+		fn := filepath.Join(dl.dir, name+".log")
+		// newest first: the current file, those full, then rotated
+		fns := []string{fn}
+		full := stamped(fn)
+		for i := len(full) - 1; i >= 0; i-- {
+			fns = append(fns, full[i])
+		}
+		for i := 1; i <= dl.keep; i++ {
+			fns = append(fns, fmt.Sprint(fn, ".", i, ".gz"))
+		}
+		for _, fn := range fns {
+			older, oldest := dl.read(fn, name, q)
+			these = append(older, these...)
+			if q.N > 0 && len(these) >= q.N {
+				these = tail(these, q.N)
+				break
+			}
+			if (q.N == 0 && q.Since.IsZero()) ||
+				(!oldest.IsZero() && oldest.Before(q.Since)) {
+				break
+			}
+		}
+		entries = append(entries, these...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].t.Before(entries[j].t)
+	})
+	return tail(entries, q.N)
+}
+
+// read the entries of the file, gzip compressed if FILE.gz, that match the
+// query; also return the time of its first entry.
+func (dl *daemonLog) read(fn string, name string,
+	q logQuery) ([]logEntry, time.Time) {
+	var (
+		r       io.Reader
+		entries []logEntry
+		oldest  time.Time
+	)
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, oldest
+	}
+	defer f.Close()
+	r = f
+	if strings.HasSuffix(fn, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, oldest
+		}
+		defer zr.Close()
+		r = zr
+	}
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		// STAMP PRIORITY ID: MSG
+		fields := strings.SplitN(scan.Text(), " ", 3)
+		if len(fields) < 3 {
+			continue
+		}
+		t, err := time.Parse(logStamp, fields[0])
+		if err != nil {
+			continue
+		}
+		if oldest.IsZero() {
+			oldest = t
+		}
+		l := logEntry{t: t, name: name, id: fields[2]}
+		l.pri = log.PriorityByName[fields[1]]
+		if j := strings.Index(l.id, ": "); j > 0 {
+			l.id, l.msg = l.id[:j], l.id[j+2:]
+		}
+		if l.match(q) {
+			entries = append(entries, l)
+		}
+	}
+	return entries, oldest
+}
+
+func tail(entries []logEntry, n int) []logEntry {
+	if n > 0 && len(entries) > n {
+		return entries[len(entries)-n:]
+	}
+	return entries
+}
+
+// serve the log stream of each connection's query.
+func (dl *daemonLog) serve(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go dl.stream(conn)
+	}
+}
+
+// stream the entries that match the query then, with Follow, those that
+// follow until the connection is closed.
+func (dl *daemonLog) stream(conn net.Conn) {
+	defer conn.Close()
+	var q logQuery
+	if err := gob.NewDecoder(conn).Decode(&q); err != nil {
+		return
+	}
+	var c chan logEntry
+	if q.Follow {
+		c = make(chan logEntry, logFollow)
+		dl.mutex.Lock()
+		dl.followers[c] = struct{}{}
+		dl.mutex.Unlock()
+		defer func() {
+			dl.mutex.Lock()
+			delete(dl.followers, c)
+			dl.mutex.Unlock()
+		}()
+	}
+	var entries []logEntry
+	if len(dl.dir) > 0 {
+		entries = dl.history(q)
+	} else {
+		entries = dl.recent(q)
+	}
+	w := bufio.NewWriter(conn)
+	var last time.Time
+	for _, l := range entries {
+		l.format(w)
+		last = l.t
+	}
+	if err := w.Flush(); err != nil || !q.Follow {
+		return
+	}
+	closed := make(chan struct{})
+	go func() {
+		io.Copy(ioutil.Discard, conn)
+		close(closed)
+	}()
+	for {
+		select {
+		case l := <-c:
+			// those already in the history
+			if !l.t.After(last) || !l.match(q) {
+				continue
+			}
+			l.format(w)
+			if err := w.Flush(); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
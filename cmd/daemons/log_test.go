@@ -0,0 +1,199 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package daemons
+
+import (
+	"fmt"
+	"log/syslog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSince(t *testing.T) {
+	now := time.Now()
+	y, m, d := now.Date()
+	for _, tc := range []struct {
+		s    string
+		want time.Time
+	}{
+		{"2017-03-04 05:06:07",
+			time.Date(2017, 3, 4, 5, 6, 7, 0, time.Local)},
+		{"2017-03-04T05:06:07",
+			time.Date(2017, 3, 4, 5, 6, 7, 0, time.Local)},
+		{"2017-03-04 05:06",
+			time.Date(2017, 3, 4, 5, 6, 0, 0, time.Local)},
+		{"2017-03-04",
+			time.Date(2017, 3, 4, 0, 0, 0, 0, time.Local)},
+		{"05:06:07", time.Date(y, m, d, 5, 6, 7, 0, time.Local)},
+		{"05:06", time.Date(y, m, d, 5, 6, 0, 0, time.Local)},
+		{"2017-03-04T05:06:07Z",
+			time.Date(2017, 3, 4, 5, 6, 7, 0, time.UTC)},
+	} {
+		got, err := since(tc.s)
+		if err != nil {
+			t.Errorf("%s: %v", tc.s, err)
+		} else if !got.Equal(tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.s, got, tc.want)
+		}
+	}
+	for _, tc := range []struct {
+		s    string
+		want time.Duration
+	}{
+		{"10", 10 * time.Second},
+		{"5m", 5 * time.Minute},
+		{"1d", 24 * time.Hour},
+	} {
+		got, err := since(tc.s)
+		if err != nil {
+			t.Errorf("%s: %v", tc.s, err)
+		} else if ago := time.Since(got); ago < tc.want ||
+			ago > tc.want+time.Second {
+			t.Errorf("%s: got %v ago, want %v", tc.s, ago, tc.want)
+		}
+	}
+	for _, s := range []string{"", "yesterday", "2017-13-01", "25:00"} {
+		if _, err := since(s); err == nil {
+			t.Errorf("%s: no error", s)
+		}
+	}
+}
+
+// messages returns those of the entries, space separated.
+func messages(entries []logEntry) string {
+	var msgs []string
+	for _, l := range entries {
+		msgs = append(msgs, l.msg)
+	}
+	return strings.Join(msgs, " ")
+}
+
+func TestRecent(t *testing.T) {
+	dl := new(daemonLog)
+	dl.init()
+	// wrap the ring
+	for i := 0; i < logEntries+2; i++ {
+		name := "a"
+		if i%2 == 1 {
+			name = "b"
+		}
+		fmt.Fprintf(dl, "<14>%s[1]: %d\n", name, i)
+	}
+	fmt.Fprint(dl, "<11>b[1]: err\n")
+	debug := int(syslog.LOG_DEBUG)
+	for _, tc := range []struct {
+		q    logQuery
+		want string
+	}{
+		{logQuery{N: 3, Priority: debug}, "128 129 err"},
+		{logQuery{N: 3, Daemon: "a", Priority: debug}, "124 126 128"},
+		{logQuery{Priority: int(syslog.LOG_ERR)}, "err"},
+		{logQuery{N: 2, Daemon: "c", Priority: debug}, ""},
+	} {
+		if got := messages(dl.recent(tc.q)); got != tc.want {
+			t.Errorf("%+v: got %q, want %q", tc.q, got, tc.want)
+		}
+	}
+	if n := len(dl.recent(logQuery{Priority: debug})); n != logEntries {
+		t.Errorf("got %d entries, want %d", n, logEntries)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	dl := &daemonLog{dir: t.TempDir()}
+	dl.init()
+	var times []time.Time
+	for i, s := range []string{
+		"<14>a[1]: 0",
+		"<14>b[2]: 1",
+		"<11>a[1]: 2",
+		"<15>b[2]: 3",
+	} {
+		times = append(times, time.Now().Truncate(time.Microsecond))
+		fmt.Fprintln(dl, s)
+		if i == 2 {
+			// restart with only the files
+			dl = &daemonLog{dir: dl.dir}
+			dl.init()
+		}
+		time.Sleep(time.Millisecond)
+	}
+	debug := int(syslog.LOG_DEBUG)
+	for _, tc := range []struct {
+		q    logQuery
+		want string
+	}{
+		{logQuery{Priority: debug}, "0 1 2 3"},
+		{logQuery{N: 2, Priority: debug}, "2 3"},
+		{logQuery{Daemon: "a", Priority: debug}, "0 2"},
+		{logQuery{Priority: int(syslog.LOG_ERR)}, "2"},
+		{logQuery{Since: times[1], Priority: debug}, "1 2 3"},
+		{logQuery{Daemon: "c", Priority: debug}, ""},
+	} {
+		if got := messages(dl.history(tc.q)); got != tc.want {
+			t.Errorf("%+v: got %q, want %q", tc.q, got, tc.want)
+		}
+	}
+}
+
+func TestRotate(t *testing.T) {
+	dl := &daemonLog{dir: t.TempDir(), size: 1, keep: 2}
+	dl.init()
+	fn := filepath.Join(dl.dir, "a.log")
+	var times []time.Time
+	for i := 0; i < 5; i++ {
+		if i == 4 {
+			// the last isn't rotated
+			dl.size = 1 << 20
+		}
+		times = append(times, time.Now().Truncate(time.Microsecond))
+		fmt.Fprintf(dl, "<14>a[1]: %d\n", i)
+		time.Sleep(time.Millisecond)
+		// that of the persist goroutine may be before or after
+		dl.rotate(fn)
+	}
+	for _, suffix := range []string{"", ".1.gz", ".2.gz"} {
+		if _, err := os.Stat(fn + suffix); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := os.Stat(fn + ".3.gz"); err == nil {
+		t.Error("kept", fn+".3.gz")
+	}
+	debug := int(syslog.LOG_DEBUG)
+	for _, tc := range []struct {
+		q    logQuery
+		want string
+	}{
+		{logQuery{Priority: debug}, "4"},
+		{logQuery{N: 2, Priority: debug}, "3 4"},
+		{logQuery{N: 10, Priority: debug}, "2 3 4"},
+		{logQuery{Since: times[3], Priority: debug}, "3 4"},
+	} {
+		if got := messages(dl.history(tc.q)); got != tc.want {
+			t.Errorf("%+v: got %q, want %q", tc.q, got, tc.want)
+		}
+	}
+	// a full file that's yet to be compressed, as by persist
+	dl.files["a"].Close()
+	delete(dl.files, "a")
+	os.Rename(fn, fmt.Sprint(fn, ".", time.Now().UnixNano()))
+	fmt.Fprint(dl, "<14>a[1]: 5\n")
+	for _, tc := range []struct {
+		q    logQuery
+		want string
+	}{
+		{logQuery{N: 2, Priority: debug}, "4 5"},
+		{logQuery{N: 10, Priority: debug}, "2 3 4 5"},
+	} {
+		if got := messages(dl.history(tc.q)); got != tc.want {
+			t.Errorf("full: %+v: got %q, want %q", tc.q, got,
+				tc.want)
+		}
+	}
+}
@@ -19,12 +19,28 @@ import (
 
 type Server struct {
 	// Machines list goes command + args for daemons that run from start,
-	// including redisd.  Note that dependent daemons should wait on a
+	// including redisd.  Note that dependent daemons should either be
+	// configured to start After or Requires redisd or wait on a
 	// respective redis key, e.g.
 	//	redis.Hwait(redis.DefaultHash, "redis.ready", "true", TIMEOUT)
 	// or
 	//	redis.IsReady()
 	Init [][]string
+	// Config has the restart policy, dependencies, etc. of daemons by
+	// name, args[0], e.g.
+	//	Config: map[string]daemons.Config{
+	//		"fspd":    {Restart: daemons.RestartAlways},
+	//		"redisd":  {Notify: true},
+	//		"w83795d": {Requires: []string{"redisd", "i2cd"}},
+	//	}
+	Config map[string]Config
+	// LogDir, e.g. /var/log/goes, has the persistent log of each daemon,
+	// NAME.log, that's rotated to NAME.log.1.gz, etc., when larger than
+	// LogSize, keeping LogKeep of these; by default, 256KiB and 4.
+	// Without LogDir, "daemon log" has just the recent entries of memory.
+	LogDir  string
+	LogSize int64
+	LogKeep int
 	Daemons
 }
 
@@ -47,7 +63,11 @@ func (*Server) Kind() cmd.Kind { return cmd.Hidden }
 func (c *Server) Main(args ...string) error {
 	var err error
 
+	c.Daemons.log.dir = c.LogDir
+	c.Daemons.log.size = c.LogSize
+	c.Daemons.log.keep = c.LogKeep
 	c.Daemons.init()
+	c.Daemons.config = c.Config
 
 	sig := make(chan os.Signal)
 	signal.Notify(sig, syscall.SIGTERM)
@@ -59,8 +79,15 @@ func (c *Server) Main(args ...string) error {
 	}
 	defer c.rpc.Close()
 
+	ln, err := atsock.Listen(logsockname)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	go c.Daemons.log.serve(ln)
+
 	for _, dargs := range c.Init {
-		c.Daemons.start(0, dargs...)
+		c.Daemons.start(dargs...)
 	}
 
 	rpc.Register(&c.Daemons)
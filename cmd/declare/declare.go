@@ -0,0 +1,104 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package declare
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/internal/flags"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "declare" }
+
+func (*Command) Usage() string {
+	return "declare [-f | -F] [NAME[=VALUE]]..."
+}
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "print functions or assign variables",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	With '-f', print the definition of each named, or every, function;
+	with '-F', just the function names. It's an error if a named function
+	isn't defined.
+
+	Otherwise, assign each named variable, which is local within a
+	function; or print the shell variables if none are named.`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind {
+	return cmd.DontFork | cmd.CantPipe | cmd.NoCLIFlags
+}
+
+func (c *Command) Main(args ...string) error {
+	flag, args := flags.New(args, "-f", "-F")
+	if flag.ByName["-f"] || flag.ByName["-F"] {
+		return c.functions(args, flag.ByName["-F"])
+	}
+	if len(args) == 0 {
+		names := make([]string, 0, len(c.g.EnvMap))
+		for name := range c.g.EnvMap {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("%s=%s\n", name, c.g.EnvMap[name])
+		}
+		return nil
+	}
+	for _, arg := range args {
+		name, value := arg, ""
+		if eq := strings.Index(arg, "="); eq >= 0 {
+			name, value = arg[:eq], arg[eq+1:]
+		}
+		if c.g.Local(name, value) != nil {
+			c.g.Setenv(name, value)
+		}
+	}
+	return nil
+}
+
+func (c *Command) functions(names []string, nameOnly bool) error {
+	if len(names) == 0 {
+		for name := range c.g.FunctionMap {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	for _, name := range names {
+		f, found := c.g.FunctionMap[name]
+		if !found {
+			return fmt.Errorf("%s: function not found", name)
+		}
+		if nameOnly {
+			fmt.Println(name)
+			continue
+		}
+		fmt.Println("function", name, "{")
+		for _, line := range f.Definition {
+			fmt.Println(line)
+		}
+		fmt.Println("}")
+	}
+	return nil
+}
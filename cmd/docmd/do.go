@@ -0,0 +1,38 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package docmd
+
+import (
+	"errors"
+
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct{}
+
+func (Command) String() string { return "do" }
+
+func (Command) Usage() string {
+	return "while COMMAND ; do COMMAND ; done"
+}
+
+func (Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "body of a loop command block",
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Begins the body of a for, while, or until loop
+`,
+	}
+}
+
+func (c Command) Main(args ...string) error {
+	return errors.New("missing for, while, or until")
+}
@@ -0,0 +1,36 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package donecmd
+
+import (
+	"errors"
+
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct{}
+
+func (Command) String() string { return "done" }
+
+func (Command) Usage() string { return "done" }
+
+func (Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "end of loop command block",
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Terminates a for, while, or until loop
+`,
+	}
+}
+
+func (c Command) Main(args ...string) error {
+	return errors.New("missing for, while, or until")
+}
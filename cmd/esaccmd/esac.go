@@ -0,0 +1,36 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package esaccmd
+
+import (
+	"errors"
+
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct{}
+
+func (Command) String() string { return "esac" }
+
+func (Command) Usage() string { return "esac" }
+
+func (Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "end of case command block",
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Terminates a case block
+`,
+	}
+}
+
+func (c Command) Main(args ...string) error {
+	return errors.New("missing case")
+}
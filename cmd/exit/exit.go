@@ -8,33 +8,40 @@ import (
 	"os"
 	"strconv"
 
+	"github.com/platinasystems/goes"
 	"github.com/platinasystems/goes/cmd"
 	"github.com/platinasystems/goes/lang"
 )
 
-type Command struct{}
+type Command struct {
+	g *goes.Goes
+}
 
-func (Command) String() string { return "exit" }
+func (*Command) String() string { return "exit" }
 
-func (Command) Usage() string { return "exit [N]" }
+func (*Command) Usage() string { return "exit [N]" }
 
-func (Command) Apropos() lang.Alt {
+func (*Command) Apropos() lang.Alt {
 	return lang.Alt{
 		lang.EnUS: "exit the shell",
 	}
 }
 
-func (Command) Man() lang.Alt {
+func (*Command) Man() lang.Alt {
 	return lang.Alt{
 		lang.EnUS: `
 DESCRIPTION
-	Exit the shell, returning a status of N, if given, or 0 otherwise.`,
+	Exit the shell, returning a status of N, if given, or 0 otherwise.
+	The shell first runs any EXIT trap, see 'man trap'. Within a
+	command substitution, this exits just its subshell.`,
 	}
 }
 
-func (Command) Kind() cmd.Kind { return cmd.DontFork | cmd.CantPipe }
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork | cmd.CantPipe }
 
-func (Command) Main(args ...string) error {
+func (c *Command) Main(args ...string) error {
 	var ecode int
 	if len(args) != 0 {
 		i64, err := strconv.ParseInt(args[0], 0, 0)
@@ -43,6 +50,10 @@ func (Command) Main(args ...string) error {
 		}
 		ecode = int(i64)
 	}
-	os.Exit(ecode)
-	return nil
+	if c.g == nil {
+		os.Exit(ecode)
+	}
+	c.g.Exit(ecode)
+	// that of a command substitution
+	return c.g.Status
 }
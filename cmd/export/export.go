@@ -9,11 +9,14 @@ import (
 	"os"
 	"strings"
 
+	"github.com/platinasystems/goes"
 	"github.com/platinasystems/goes/cmd"
 	"github.com/platinasystems/goes/lang"
 )
 
-type Command struct{}
+type Command struct {
+	g *goes.Goes
+}
 
 func (Command) String() string { return "export" }
 
@@ -31,16 +34,22 @@ func (Command) Man() lang.Alt {
 DESCRIPTION
 	Configure the named process environment parameter.
 
-	If no VALUE is given, NAME is reset.
+	If no VALUE is given, the shell variable NAME is exported or, if
+	there isn't one, NAME is reset.
+
+	Only exported variables, and those assigned by the command line,
+	e.g. "NAME=VALUE program", are in the environment of programs.
 
 	If no NAMES are supplied, a list of names of all exported variables
 	is printed.`,
 	}
 }
 
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
 func (Command) Kind() cmd.Kind { return cmd.DontFork | cmd.CantPipe }
 
-func (Command) Main(args ...string) error {
+func (c *Command) Main(args ...string) error {
 	if len(args) == 0 {
 		for _, nv := range os.Environ() {
 			fmt.Println(nv)
@@ -48,14 +57,33 @@ func (Command) Main(args ...string) error {
 		return nil
 	}
 	for _, arg := range args {
+		name, value := arg, ""
 		eq := strings.Index(arg, "=")
-		if eq < 0 {
-			if err := os.Unsetenv(arg); err != nil {
+		if eq >= 0 {
+			name, value = arg[:eq], arg[eq+1:]
+		} else if v, def := c.shellVar(name); def {
+			value = v
+		} else {
+			if err := os.Unsetenv(name); err != nil {
 				return err
 			}
-		} else if err := os.Setenv(arg[:eq], arg[eq+1:]); err != nil {
+			continue
+		}
+		if err := os.Setenv(name, value); err != nil {
 			return err
 		}
+		// the process environment now has that of the shell
+		if c.g != nil {
+			delete(c.g.EnvMap, name)
+		}
 	}
 	return nil
 }
+
+func (c *Command) shellVar(name string) (string, bool) {
+	if c.g == nil {
+		return "", false
+	}
+	v, def := c.g.EnvMap[name]
+	return v, def
+}
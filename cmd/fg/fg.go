@@ -0,0 +1,79 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package fg
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "fg" }
+
+func (*Command) Usage() string { return "fg [%N]" }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "wait for a background job in the foreground",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Wait for the given, or most recent, background job to finish while
+	forwarding interrupts (Ctrl-C) to its processes. The exit status is
+	that of the job.
+
+	See 'man wait' for the job specification.`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork | cmd.CantPipe }
+
+func (c *Command) Main(args ...string) error {
+	spec := "%%"
+	switch len(args) {
+	case 0:
+	case 1:
+		spec = args[0]
+	default:
+		return fmt.Errorf("%v: unexpected", args[1:])
+	}
+	j, err := c.g.FindJob(spec)
+	if err != nil {
+		return err
+	}
+	fmt.Println(strings.TrimSuffix(j.Cmd, " &"))
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT)
+	defer c.g.IgnoreSignal(syscall.SIGINT)
+	defer signal.Stop(sig)
+
+	done := make(chan error, 1)
+	go func() { done <- c.g.WaitJob(j) }()
+	for {
+		select {
+		case err = <-done:
+			return err
+		case <-sig:
+			j.Signal(syscall.SIGINT)
+		}
+	}
+}
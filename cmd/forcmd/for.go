@@ -0,0 +1,155 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package forcmd
+
+import (
+	"errors"
+	"io"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/internal/shellutils"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct{}
+
+func (Command) String() string { return "for" }
+
+func (Command) Usage() string {
+	return "for NAME in [WORD]... ; do COMMAND ; done"
+}
+
+func (Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "repeat commands for each word of a list",
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Executes the commands following 'do' once for each WORD with the
+	variable NAME set to that WORD.
+
+	The 'break' and 'continue' commands may be used to exit the loop or
+	skip to its next pass.
+
+EXAMPLES
+	for port in 1 2 3 4 ; do
+		echo qsfp $port
+	done`,
+	}
+}
+
+func (c Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func(stdin io.Reader, stdout io.Writer, stderr io.Writer, isFirst bool, isLast bool) error, error) {
+	var doList []func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error
+	// for NAME in WORD...
+	cl := ls.Cmds[0]
+	if len(cl.Cmds) < 2 {
+		return nil, nil, cl.Pos.Errorf("for: NAME: missing")
+	}
+	name := cl.Cmds[1].String()
+	if len(cl.Cmds) < 3 || cl.Cmds[2].String() != "in" {
+		return nil, nil, cl.Pos.Errorf("for: 'in': missing")
+	}
+	if term := cl.Term.String(); term != "" && term != ";" {
+		return nil, nil, cl.Term.Pos.Errorf("Unexpected '%s'", term)
+	}
+	words := cl
+	pos := cl.Cmds[0].Pos
+	ls.Cmds = ls.Cmds[1:]
+	for {
+		for len(ls.Cmds) == 0 {
+			newls, err := g.ParseBlock("for>", pos, "done")
+			if err != nil {
+				return nil, nil, err
+			}
+			ls = *newls
+		}
+		cl := ls.Cmds[0]
+		kw := ""
+		if len(cl.Cmds) > 0 {
+			kw = cl.Cmds[0].String()
+		}
+		if kw == "do" {
+			if doList != nil {
+				return nil, nil, cl.Pos.Errorf("Unexpected 'do'")
+			}
+			doList = make([]func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error, 0)
+			if len(cl.Cmds) > 1 {
+				cl.Cmds = cl.Cmds[1:]
+				ls.Cmds[0] = cl
+			} else {
+				ls.Cmds = ls.Cmds[1:]
+			}
+			continue
+		}
+		if doList == nil {
+			return nil, nil, cl.Pos.Errorf("Unexpected '%s'", kw)
+		}
+		if kw == "done" {
+			if len(doList) == 0 {
+				return nil, nil, cl.Pos.Errorf("Unexpected 'done'")
+			}
+			if !cl.IsRedirects(1) {
+				return nil, nil, cl.Cmds[1].Pos.Errorf(
+					"unexpected text after done")
+			}
+			break
+		}
+		nextls, _, runfun, err := g.ProcessList(ls)
+		if err != nil {
+			return nil, nil, err
+		}
+		doList = append(doList, runfun)
+		ls = *nextls
+	}
+	blockfun, err := makeBlockFunc(g, name, words, doList)
+
+	return &ls, blockfun, err
+}
+
+func runList(pipeline []func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	for _, runent := range pipeline {
+		err := runent(stdin, stdout, stderr)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func makeBlockFunc(g *goes.Goes, name string, words shellutils.Cmdline, doList []func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error) (func(stdin io.Reader, stdout io.Writer, stderr io.Writer, isFirst bool, isLast bool) error, error) {
+	runfun := func(stdin io.Reader, stdout io.Writer, stderr io.Writer, isFirst bool, isLast bool) error {
+		// expand the words of "for NAME in WORD..." as command arguments
+		_, args, err := words.Slice(g)
+		if err != nil {
+			return err
+		}
+		g.Status = nil
+		g.EnterLoop()
+		defer g.ExitLoop()
+		for _, arg := range args[3:] {
+			if g.EnvMap == nil {
+				g.EnvMap = make(map[string]string)
+			}
+			g.EnvMap[name] = arg
+			err := runList(doList, stdin, stdout, stderr)
+			if err != nil {
+				return err
+			}
+			if g.EndOfPass() {
+				break
+			}
+		}
+		return nil
+	}
+	return runfun, nil
+}
+
+func (Command) Main(args ...string) error {
+	return errors.New("internal error")
+}
@@ -6,8 +6,8 @@ package function
 
 import (
 	"errors"
-	"fmt"
 	"io"
+	"strings"
 
 	"github.com/platinasystems/goes"
 	"github.com/platinasystems/goes/lang"
@@ -21,7 +21,26 @@ const (
 	Usage   = "function name { definition }"
 	Man     = `
 DESCRIPTION
-	Define a function.
+	Define a function. The arguments of its invocation are the positional
+	parameters, $1 through $N, while the function runs.
+
+	The function may assign variables that are restored when it returns
+	with 'local NAME=VALUE'; and may return early with 'return [N]'. Its
+	status is N, or otherwise that of the last command run.
+
+	Functions may call themselves to a nesting depth of 1000.
+
+	See 'declare -f' to print, and 'unset -f' to remove, functions.
+
+EXAMPLES
+	function fact {
+		if [ $1 -le 1 ]; then
+			echo 1
+			return
+		fi
+		local n=$(fact $(($1 - 1)))
+		echo $(($1 * n))
+	}
 `
 )
 
@@ -43,9 +62,10 @@ func (Command) Usage() string     { return Usage }
 
 func (Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func(stdin io.Reader, stdout io.Writer, stderr io.Writer, isFirst bool, isLast bool) error, error) {
 	cl := ls.Cmds[0]
+	pos := cl.Cmds[0].Pos
 	// function name { definition ... ; }
 	if len(cl.Cmds) < 2 {
-		return nil, nil, errors.New("Function: unexpected end of line")
+		return nil, nil, pos.Errorf("Function: unexpected end of line")
 	}
 
 	name := cl.Cmds[1].String()
@@ -53,7 +73,7 @@ func (Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func(s
 	for len(cl.Cmds) < 1 {
 		ls.Cmds = ls.Cmds[1:]
 		for len(ls.Cmds) == 0 {
-			newls, err := shellutils.Parse("function>", g.Catline)
+			newls, err := g.ParseBlock("function>", pos, "}")
 			if err != nil {
 				return nil, nil, err
 			}
@@ -62,7 +82,7 @@ func (Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func(s
 		cl = ls.Cmds[0]
 	}
 	if cl.Cmds[0].String() != "{" {
-		return nil, nil, fmt.Errorf("Function: unexpected %s",
+		return nil, nil, cl.Pos.Errorf("Function: unexpected %s",
 			cl.Cmds[0].String())
 	}
 	if len(cl.Cmds) > 1 {
@@ -72,26 +92,43 @@ func (Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func(s
 		ls.Cmds = ls.Cmds[1:]
 	}
 
+	// record the definition from the rest of this line and those read,
+	// up to the closing brace
+	first := append([]shellutils.Cmdline{}, ls.Cmds...)
+	var def []string
+	catline := g.Catline
+	defer func() { g.Catline = catline }()
+	g.Catline = func(prompt string) (string, error) {
+		s, err := catline(prompt)
+		if err == nil {
+			def = append(def, s)
+		}
+		return s, err
+	}
+
 	var funList []func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error
 	for {
+		// e.g. the rest of "function f {"
+		var err error
+		ls, err = refill(g, ls, pos)
+		if err != nil {
+			return nil, nil, err
+		}
 		nextls, _, runfun, err := g.ProcessList(ls)
 		if err != nil {
 			return nil, nil, err
 		}
 		funList = append(funList, runfun)
-		ls = *nextls
-		for len(ls.Cmds) == 0 {
-			newls, err := shellutils.Parse("function>", g.Catline)
-			if err != nil {
-				return nil, nil, err
-			}
-			ls = *newls
+		ls, err = refill(g, *nextls, pos)
+		if err != nil {
+			return nil, nil, err
 		}
-		cl := ls.Cmds[0]
+		cl = ls.Cmds[0]
 		name := cl.Cmds[0].String()
 		if name == "}" {
 			if len(cl.Cmds) > 1 {
-				return nil, nil, errors.New("unexpected text after }")
+				return nil, nil, cl.Cmds[1].Pos.Errorf(
+					"unexpected text after }")
 			}
 			break
 		}
@@ -107,7 +144,29 @@ func (Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func(s
 		}
 		return nil
 	}
-	f := goes.Function{Name: name, RunFun: runfun}
+	if len(def) == 0 {
+		// the closing brace is on the first line
+		if n := len(first) - len(ls.Cmds); n > 0 && n <= len(first) {
+			def = append(def, "\t"+(&shellutils.List{
+				Cmds: first[:n],
+			}).Source())
+		}
+	} else {
+		if len(first) > 0 {
+			def = append([]string{"\t" + (&shellutils.List{
+				Cmds: first,
+			}).Source()}, def...)
+		}
+		// less the closing brace and anything that follows
+		n := len(def) - 1
+		if col := cl.Cmds[0].Pos.Column; col > 0 && col <= len(def[n]) {
+			def[n] = strings.TrimRight(def[n][:col-1], " \t")
+		}
+		if len(strings.TrimSpace(def[n])) == 0 {
+			def = def[:n]
+		}
+	}
+	f := goes.Function{Name: name, Definition: def, RunFun: runfun}
 
 	deffun := func(stdin io.Reader, stdout io.Writer, stderr io.Writer, isFirst bool, isLast bool) error {
 		if g.FunctionMap == nil {
@@ -119,6 +178,18 @@ func (Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func(s
 	return &ls, deffun, nil
 }
 
+// refill reads the next lines of the definition if the list is empty.
+func refill(g *goes.Goes, ls shellutils.List, pos shellutils.Pos) (shellutils.List, error) {
+	for len(ls.Cmds) == 0 {
+		newls, err := g.ParseBlock("function>", pos, "}")
+		if err != nil {
+			return ls, err
+		}
+		ls = *newls
+	}
+	return ls, nil
+}
+
 func (Command) Main(args ...string) error {
 	return errors.New("internal error")
 }
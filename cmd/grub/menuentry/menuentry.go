@@ -48,6 +48,7 @@ const Man = "NOP command for script compatibility\n"
 
 func (c *Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func(stdin io.Reader, stdout io.Writer, stderr io.Writer, isFirst bool, isLast bool) error, error) {
 	cl := ls.Cmds[0]
+	pos := cl.Pos
 	// menuentry name [option...] { definition ... ; }
 	if len(cl.Cmds) < 2 {
 		return nil, nil, errors.New("Menuentry: unexpected end of line")
@@ -93,7 +94,7 @@ func (c *Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, fun
 		funList = append(funList, runfun)
 		ls = *nextls
 		for len(ls.Cmds) == 0 {
-			newls, err := shellutils.Parse("menuentry>", g.Catline)
+			newls, err := g.ParseBlock("menuentry>", pos, "}")
 			if err != nil {
 				return nil, nil, err
 			}
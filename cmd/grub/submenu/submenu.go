@@ -44,6 +44,7 @@ const Man = "NOP command for script compatibility\n"
 
 func (c Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func(stdin io.Reader, stdout io.Writer, stderr io.Writer, isFirst bool, isLast bool) error, error) {
 	cl := ls.Cmds[0]
+	pos := cl.Pos
 	// submenu name { definition ... ; }
 	if len(cl.Cmds) < 2 {
 		return nil, nil, errors.New("Submenu: unexpected end of line")
@@ -80,7 +81,7 @@ func (c Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func
 	var funList []func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error
 	for {
 		for len(ls.Cmds) == 0 {
-			newls, err := shellutils.Parse("submenu>", g.Catline)
+			newls, err := g.ParseBlock("submenu>", pos, "}")
 			if err != nil {
 				return nil, nil, err
 			}
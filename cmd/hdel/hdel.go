@@ -7,6 +7,7 @@ package hdel
 import (
 	"fmt"
 
+	"github.com/platinasystems/goes/internal/rediscomplete"
 	"github.com/platinasystems/goes/lang"
 	"github.com/platinasystems/redis"
 )
@@ -24,7 +25,7 @@ func (Command) Apropos() lang.Alt {
 }
 
 func (Command) Complete(args ...string) []string {
-	return redis.Complete(args...)
+	return rediscomplete.KeyField(args...)
 }
 
 func (Command) Main(args ...string) error {
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	redigo "github.com/garyburd/redigo/redis"
+	"github.com/platinasystems/goes/internal/rediscomplete"
 	"github.com/platinasystems/goes/lang"
 	"github.com/platinasystems/redis"
 )
@@ -45,6 +46,10 @@ DESCRIPTION
 	}
 }
 
+func (*Command) Complete(args ...string) []string {
+	return rediscomplete.Keys(args...)
+}
+
 func (c *Command) Main(args ...string) error {
 	switch len(args) {
 	case 0:
@@ -7,6 +7,7 @@ package hexists
 import (
 	"fmt"
 
+	"github.com/platinasystems/goes/internal/rediscomplete"
 	"github.com/platinasystems/goes/lang"
 	"github.com/platinasystems/redis"
 )
@@ -23,7 +24,7 @@ func (Command) Apropos() lang.Alt {
 }
 
 func (Command) Complete(args ...string) []string {
-	return redis.Complete(args...)
+	return rediscomplete.KeyField(args...)
 }
 
 func (Command) Main(args ...string) error {
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/platinasystems/goes/internal/rediscomplete"
 	"github.com/platinasystems/goes/lang"
 	"github.com/platinasystems/redis"
 )
@@ -43,5 +44,5 @@ func (Command) Main(args ...string) error {
 }
 
 func (Command) Complete(args ...string) []string {
-	return redis.Complete(args...)
+	return rediscomplete.KeyField(args...)
 }
@@ -7,6 +7,7 @@ package hgetall
 import (
 	"fmt"
 
+	"github.com/platinasystems/goes/internal/rediscomplete"
 	"github.com/platinasystems/goes/lang"
 	"github.com/platinasystems/redis"
 )
@@ -55,5 +56,5 @@ func (Command) Main(args ...string) error {
 }
 
 func (Command) Complete(args ...string) []string {
-	return redis.Complete(args...)
+	return rediscomplete.Keys(args...)
 }
@@ -0,0 +1,100 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package history
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/internal/flags"
+	"github.com/platinasystems/goes/internal/histfile"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "history" }
+
+func (*Command) Usage() string { return "history [-c] [N]" }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "print or clear the command line history",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Print the numbered command line history of the interactive cli,
+	oldest first, or just the last N lines.
+
+	The history of each user is kept in $HISTFILE, by default
+	$HOME/.goes_history, which is shared by concurrent sessions, e.g.
+	those of the console, ssh, and telnet. It's limited to the last
+	$HISTSIZE, by default 1000, distinct lines.
+
+	A command line may refer to the history with these, other than
+	within single quotes or escaped with backslash.
+
+		!!	the previous command line
+		!N	command line N of the history
+		!-N	the Nth previous command line
+		!PREFIX	the most recent command line beginning with PREFIX
+
+	Ctrl-R searches the history backwards for the entered text.
+
+OPTIONS
+	-c	clear the history
+
+EXAMPLES
+	history 20
+	history | grep ip
+	!!
+	!ip`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork }
+
+func (c *Command) Main(args ...string) error {
+	flag, args := flags.New(args, "-c")
+	h := histfile.New(histfile.Name(c.g.Getenv), histfile.Size(c.g.Getenv))
+	if flag.ByName["-c"] {
+		if len(args) > 0 {
+			return fmt.Errorf("%v: unexpected", args)
+		}
+		return h.Clear()
+	}
+	if err := h.Load(); err != nil {
+		return err
+	}
+	lines := h.Lines()
+	first := 0
+	switch len(args) {
+	case 0:
+	case 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 0 {
+			return fmt.Errorf("%s: invalid number", args[0])
+		}
+		if n < len(lines) {
+			first = len(lines) - n
+		}
+	default:
+		return fmt.Errorf("%v: unexpected", args[1:])
+	}
+	for i := first; i < len(lines); i++ {
+		fmt.Printf("%5d  %s\n", i+1, lines[i])
+	}
+	return nil
+}
@@ -7,8 +7,9 @@ package hset
 import (
 	"fmt"
 
-	"github.com/platinasystems/goes/lang"
 	"github.com/platinasystems/goes/internal/flags"
+	"github.com/platinasystems/goes/internal/rediscomplete"
+	"github.com/platinasystems/goes/lang"
 	"github.com/platinasystems/redis"
 )
 
@@ -48,5 +49,5 @@ func (Command) Main(args ...string) error {
 }
 
 func (Command) Complete(args ...string) []string {
-	return redis.Complete(args...)
+	return rediscomplete.KeyField(args...)
 }
@@ -8,6 +8,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/platinasystems/goes/internal/duration"
+	"github.com/platinasystems/goes/internal/parms"
+	"github.com/platinasystems/goes/internal/rediscomplete"
 	"github.com/platinasystems/goes/lang"
 	"github.com/platinasystems/redis"
 )
@@ -17,7 +20,7 @@ type Command struct{}
 func (Command) String() string { return "hwait" }
 
 func (Command) Usage() string {
-	return "hwait KEY FIELD VALUE [TIMEOUT(seconds)]"
+	return "hwait [-t DURATION] KEY FIELD VALUE [TIMEOUT(seconds)]"
 }
 
 func (Command) Apropos() lang.Alt {
@@ -26,8 +29,26 @@ func (Command) Apropos() lang.Alt {
 	}
 }
 
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Wait until the redis hash field has the given VALUE, for at most
+	DURATION, by default 3 seconds. The DURATION is as described by
+	'man timeout', e.g. 2.5 or 1m30s; the TIMEOUT argument is an older
+	form of -t in whole seconds.`,
+	}
+}
+
 func (Command) Main(args ...string) error {
-	n := time.Duration(3)
+	parm, args := parms.New(args, "-t")
+	d := 3 * time.Second
+	if s := parm.ByName["-t"]; len(s) > 0 {
+		var err error
+		if d, err = duration.Parse(s); err != nil {
+			return err
+		}
+	}
 	switch len(args) {
 	case 0:
 		return fmt.Errorf("KEY FIELD: missing")
@@ -37,15 +58,17 @@ func (Command) Main(args ...string) error {
 		return fmt.Errorf("VALUE: missing")
 	case 3:
 	case 4:
+		var n time.Duration
 		if _, err := fmt.Sscan(args[3], &n); err != nil {
 			return err
 		}
+		d = n * time.Second
 	default:
 		return fmt.Errorf("%v: unexpected", args[4:])
 	}
-	return redis.Hwait(args[0], args[1], args[2], n*time.Second)
+	return redis.Hwait(args[0], args[1], args[2], d)
 }
 
 func (Command) Complete(args ...string) []string {
-	return redis.Complete(args...)
+	return rediscomplete.KeyField(args...)
 }
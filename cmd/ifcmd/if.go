@@ -39,6 +39,7 @@ func (c Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func
 	var ifList, thenList, elseList []func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error
 	curList := &ifList
 	cl := ls.Cmds[0]
+	pos := cl.Cmds[0].Pos
 	// if <command>
 	if len(cl.Cmds) > 1 {
 		cl.Cmds = cl.Cmds[1:]
@@ -47,24 +48,26 @@ func (c Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func
 		ls.Cmds = ls.Cmds[1:]
 	}
 	for {
+		// e.g. the rest of "if true; then"
+		var err error
+		ls, err = refill(g, ls, pos)
+		if err != nil {
+			return nil, nil, err
+		}
 		nextls, _, runfun, err := g.ProcessList(ls)
 		if err != nil {
 			return nil, nil, err
 		}
 		*curList = append(*curList, runfun)
-		ls = *nextls
-		for len(ls.Cmds) == 0 {
-			newls, err := shellutils.Parse("if>", g.Catline)
-			if err != nil {
-				return nil, nil, err
-			}
-			ls = *newls
+		ls, err = refill(g, *nextls, pos)
+		if err != nil {
+			return nil, nil, err
 		}
 		cl := ls.Cmds[0]
 		name := cl.Cmds[0].String()
 		if name == "then" {
 			if curList != &ifList {
-				return nil, nil, errors.New("Unexpected 'then'")
+				return nil, nil, cl.Pos.Errorf("Unexpected 'then'")
 			}
 			curList = &thenList
 			if len(cl.Cmds) > 1 {
@@ -76,7 +79,7 @@ func (c Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func
 		}
 		if name == "else" {
 			if curList != &thenList {
-				return nil, nil, errors.New("Unexpected 'else'")
+				return nil, nil, cl.Pos.Errorf("Unexpected 'else'")
 			}
 			curList = &elseList
 			if len(cl.Cmds) > 1 {
@@ -88,7 +91,7 @@ func (c Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func
 		}
 		if name == "elif" {
 			if curList != &thenList {
-				return nil, nil, errors.New("Unexpected 'elif'")
+				return nil, nil, cl.Pos.Errorf("Unexpected 'elif'")
 			}
 			newls, elifFun, err := c.Block(g, ls)
 			if err != nil {
@@ -104,10 +107,10 @@ func (c Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func
 		}
 		if name == "fi" {
 			if curList != &thenList && curList != &elseList {
-				return nil, nil, errors.New("Unexpected 'fi'")
+				return nil, nil, cl.Pos.Errorf("Unexpected 'fi'")
 			}
-			if len(cl.Cmds) > 1 {
-				return nil, nil, errors.New("unexpected text after fi")
+			if !cl.IsRedirects(1) {
+				return nil, nil, cl.Cmds[1].Pos.Errorf("unexpected text after fi")
 			}
 			break
 		}
@@ -128,12 +131,27 @@ func runList(pipeline []func(stdin io.Reader, stdout io.Writer, stderr io.Writer
 	return nil
 }
 
+// refill reads the next lines of the block if the list is empty.
+func refill(g *goes.Goes, ls shellutils.List, pos shellutils.Pos) (shellutils.List, error) {
+	for len(ls.Cmds) == 0 {
+		newls, err := g.ParseBlock("if>", pos, "fi")
+		if err != nil {
+			return ls, err
+		}
+		ls = *newls
+	}
+	return ls, nil
+}
+
 func makeBlockFunc(g *goes.Goes, ifList, thenList, elseList []func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error) (func(stdin io.Reader, stdout io.Writer, stderr io.Writer, isFirst bool, isLast bool) error, error) {
 	runfun := func(stdin io.Reader, stdout io.Writer, stderr io.Writer, isFirst bool, isLast bool) error {
+		g.EnterCondition()
 		err := runList(ifList, stdin, stdout, stderr)
+		g.ExitCondition()
 		if err == nil && g.Status == nil {
 			err = runList(thenList, stdin, stdout, stderr)
 		} else {
+			g.Status = nil
 			err = runList(elseList, stdin, stdout, stderr)
 		}
 		return err
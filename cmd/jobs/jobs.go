@@ -0,0 +1,61 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package jobs
+
+import (
+	"fmt"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/internal/flags"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "jobs" }
+
+func (*Command) Usage() string { return "jobs [-l]" }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "print background jobs",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Print the number, state, and command of each background job started
+	with the '&' list terminator. Finished jobs are removed from the table
+	after they are printed.
+
+OPTIONS
+	-l	Also print the process ids of each job.`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork }
+
+func (c *Command) Main(args ...string) error {
+	flag, args := flags.New(args, "-l")
+	if len(args) > 0 {
+		return fmt.Errorf("%v: unexpected", args)
+	}
+	for _, j := range c.g.Jobs() {
+		if flag.ByName["-l"] {
+			fmt.Println(j, j.Pids())
+		} else {
+			fmt.Println(j)
+		}
+	}
+	c.g.PruneJobs()
+	return nil
+}
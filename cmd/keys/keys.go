@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/platinasystems/goes/internal/rediscomplete"
 	"github.com/platinasystems/goes/lang"
 	"github.com/platinasystems/redis"
 )
@@ -45,5 +46,5 @@ func (Command) Main(args ...string) error {
 }
 
 func (Command) Complete(args ...string) []string {
-	return redis.Complete(args...)
+	return rediscomplete.Keys(args...)
 }
@@ -11,6 +11,8 @@ import (
 	"strings"
 	"syscall"
 
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
 	"github.com/platinasystems/goes/lang"
 	"github.com/platinasystems/goes/internal/flags"
 )
@@ -53,19 +55,21 @@ var sigByOptName = map[string]syscall.Signal{
 	"-xfsz":   syscall.SIGXFSZ,
 }
 
-type Command struct{}
+type Command struct {
+	g *goes.Goes
+}
 
-func (Command) String() string { return "kill" }
+func (*Command) String() string { return "kill" }
 
-func (Command) Usage() string { return "kill [OPTION] [PID]..." }
+func (*Command) Usage() string { return "kill [OPTION] [PID | %N]..." }
 
-func (Command) Apropos() lang.Alt {
+func (*Command) Apropos() lang.Alt {
 	return lang.Alt{
 		lang.EnUS: "signal a process",
 	}
 }
 
-func (Command) Man() lang.Alt {
+func (*Command) Man() lang.Alt {
 	return lang.Alt{
 		lang.EnUS: `
 DESCRIPTION
@@ -77,6 +81,10 @@ DESCRIPTION
 	output.  A PID of -1 is special; it indicates all processes except the
 	kill process itself and init.
 
+	A background job may be given instead of PID as '%' followed by its
+	number, e.g. '%1'; see 'man wait'. This signals every process of the
+	job.
+
 OPTIONS
 	<PID> [...]
 		Send signal to every <PID> listed.
@@ -92,11 +100,18 @@ EXAMPLES
 		Kill all processes you can kill.
 
 	kill 123 543 2341 3453
-		Send the default signal, SIGTERM, to all those processes.`,
+		Send the default signal, SIGTERM, to all those processes.
+
+	kill -int %2
+		Interrupt the processes of the second background job.`,
 	}
 }
 
-func (Command) Main(args ...string) error {
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork }
+
+func (c *Command) Main(args ...string) error {
 	flag, args := flags.New(args, "-l")
 
 	sigByOptNumb := make(map[string]syscall.Signal)
@@ -172,6 +187,19 @@ func (Command) Main(args ...string) error {
 		}
 	}
 	for _, arg := range args {
+		if strings.HasPrefix(arg, "%") {
+			if c.g == nil {
+				return fmt.Errorf("%s: no job control", arg)
+			}
+			j, err := c.g.FindJob(arg)
+			if err != nil {
+				return err
+			}
+			if err = j.Signal(sig); err != nil {
+				return err
+			}
+			continue
+		}
 		pid, err := strconv.ParseInt(arg, 0, 0)
 		if err != nil {
 			return err
@@ -0,0 +1,91 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package let
+
+import (
+	"fmt"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/internal/shellutils"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "let" }
+
+func (*Command) Usage() string { return "let EXPRESSION..." }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "evaluate arithmetic expressions",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Evaluate each integer EXPRESSION, as with $((EXPRESSION)), assigning
+	any variables in the cli context. The exit status is 1 if the value
+	of the last EXPRESSION is 0; otherwise 0.
+
+	These are the operators by decreasing precedence.
+
+		VAR++ VAR--		post-increment and decrement
+		++VAR --VAR		pre-increment and decrement
+		- + ! ~			unary minus, plus, logical and bitwise not
+		**			exponentiation
+		* / %			multiply, divide, remainder
+		+ -			add, subtract
+		<< >>			left and right shift
+		<= >= < >		comparison
+		== !=			equality and inequality
+		&			bitwise AND
+		^			bitwise exclusive OR
+		|			bitwise OR
+		&&			logical AND
+		||			logical OR
+		COND ? EXPR : EXPR	conditional
+		= *= /= %= += -= <<= >>= &= ^= |=
+					assignment
+		,			sequence
+
+	Variables are referenced by name, without '$', and have the value 0
+	if unset or empty. Numbers are decimal, octal with a leading 0, or
+	hex with a leading 0x.
+
+EXAMPLES
+	let i++ "duty = pwm * 100 / 0xff"
+	while let "retry -= 1"; do
+		...
+	done`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork }
+
+func (c *Command) Main(args ...string) error {
+	var v int64
+	if len(args) == 0 {
+		return fmt.Errorf("EXPRESSION: missing")
+	}
+	for _, arg := range args {
+		var err error
+		v, err = shellutils.Arith(arg, c.g)
+		if err != nil {
+			return err
+		}
+	}
+	if v == 0 {
+		return goes.ExitStatus(1)
+	}
+	return nil
+}
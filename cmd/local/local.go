@@ -0,0 +1,67 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package local
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "local" }
+
+func (*Command) Usage() string { return "local NAME[=VALUE]..." }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "assign function variables",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Assign each named variable, empty if without VALUE, until the running
+	function returns; then restore its previous value. The functions that
+	it calls see the local variables.
+
+EXAMPLES
+	function count {
+		local i=0
+		while [ $i -lt $1 ]; do
+			let i++
+			echo $i
+		done
+	}`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork | cmd.CantPipe }
+
+func (c *Command) Main(args ...string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("NAME: missing")
+	}
+	for _, arg := range args {
+		name, value := arg, ""
+		if eq := strings.Index(arg, "="); eq >= 0 {
+			name, value = arg[:eq], arg[eq+1:]
+		}
+		if err := c.g.Local(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,309 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package printf
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct{}
+
+func (Command) String() string { return "printf" }
+
+func (Command) Usage() string { return "printf FORMAT [ARGUMENT]..." }
+
+func (Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "format and print data",
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Print the ARGUMENT(s) to standard output as directed by FORMAT.
+	FORMAT is reused while there are ARGUMENTs left; a missing ARGUMENT
+	is an empty string or zero.
+
+	FORMAT may have these escapes,
+
+		\\	backslash
+		\a	alert
+		\b	backspace
+		\f	form feed
+		\n	newline
+		\r	carriage return
+		\t	horizontal tab
+		\v	vertical tab
+		\NNN	the byte with octal value NNN, 1 to 3 digits
+
+	and these conversions of the next ARGUMENT,
+
+		%d, %i	signed decimal
+		%o	unsigned octal
+		%u	unsigned decimal
+		%x, %X	unsigned hexadecimal
+		%c	the first character
+		%s	string
+		%b	string with the above escapes, where \0NNN is octal and
+			\c stops all output
+		%e, %E, %f, %F, %g, %G
+			floating point
+		%%	a percent sign
+
+	A conversion may have the flags "-" (left justify), "+" (signed),
+	" " (space if positive), "#" (alternate form), and "0" (zero pad);
+	then, a minimum field width and a "." precision. Either may be "*"
+	for the value of the next ARGUMENT.
+
+	An integer ARGUMENT may be octal with a leading 0, hexadecimal with a
+	leading 0x, or the value of the character after a leading quote.
+
+EXIT STATUS
+	1 if an ARGUMENT isn't a valid number, although it's printed as zero.
+
+EXAMPLES
+	printf "%s=%d\n" answer 42
+	printf "%-10s|%5.2f|\n" pi 3.14159
+	printf "%x %o %#x\n" 255 8 255`,
+	}
+}
+
+func (Command) Main(args ...string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("FORMAT: missing")
+	}
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	p := &printer{w: w, args: args[1:]}
+	for {
+		n := len(p.args)
+		if p.format(args[0]) || len(p.args) == 0 || len(p.args) == n {
+			break
+		}
+	}
+	if p.failed {
+		return goes.ExitStatus(1)
+	}
+	return nil
+}
+
+type printer struct {
+	w      *bufio.Writer
+	args   []string
+	failed bool
+}
+
+// format prints the format once and returns true if stopped with \c.
+func (p *printer) format(format string) bool {
+	for i := 0; i < len(format); i++ {
+		switch c := format[i]; c {
+		case '\\':
+			n, stop := p.escape(format[i+1:], false)
+			if stop {
+				return true
+			}
+			i += n
+		case '%':
+			n, stop := p.conversion(format[i+1:])
+			if stop {
+				return true
+			}
+			i += n
+		default:
+			p.w.WriteByte(c)
+		}
+	}
+	return false
+}
+
+// escape writes the character of the backslash escape at the beginning of s
+// and returns its length. With b, as for %b, octal escapes have a leading
+// 0 and \c stops output.
+func (p *printer) escape(s string, b bool) (int, bool) {
+	if len(s) == 0 {
+		p.w.WriteByte('\\')
+		return 0, false
+	}
+	if c := strings.IndexByte("abfnrtv", s[0]); c >= 0 {
+		p.w.WriteByte("\a\b\f\n\r\t\v"[c])
+		return 1, false
+	}
+	switch {
+	case s[0] == '\\':
+		p.w.WriteByte('\\')
+		return 1, false
+	case s[0] == 'c' && b:
+		return 1, true
+	case s[0] >= '0' && s[0] <= '7':
+		i, max := 0, 3
+		if b && s[0] == '0' {
+			i, max = 1, 4
+		}
+		v := 0
+		for ; i < max && i < len(s) && s[i] >= '0' && s[i] <= '7'; i++ {
+			v = v*8 + int(s[i]-'0')
+		}
+		p.w.WriteByte(byte(v))
+		return i, false
+	}
+	p.w.WriteByte('\\')
+	return 0, false
+}
+
+// conversion writes the next argument as specified after the '%' at the
+// beginning of s and returns the length of the specification.
+func (p *printer) conversion(s string) (int, bool) {
+	if len(s) > 0 && s[0] == '%' {
+		p.w.WriteByte('%')
+		return 1, false
+	}
+	spec := []byte{'%'}
+	i := 0
+	for ; i < len(s) && strings.IndexByte("-+ #0", s[i]) >= 0; i++ {
+		spec = append(spec, s[i])
+	}
+	for _, dot := range []bool{false, true} {
+		if dot {
+			if i == len(s) || s[i] != '.' {
+				break
+			}
+			spec = append(spec, '.')
+			i++
+		}
+		if i < len(s) && s[i] == '*' {
+			spec = strconv.AppendInt(spec, p.integer(p.next()), 10)
+			i++
+			continue
+		}
+		for ; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+			spec = append(spec, s[i])
+		}
+	}
+	if i == len(s) {
+		fmt.Fprintf(os.Stderr, "printf: %%%s: missing conversion\n", s)
+		p.failed = true
+		p.w.WriteString("%" + s)
+		return i, false
+	}
+	verb := s[i]
+	i++
+	switch verb {
+	case 'd', 'i':
+		fmt.Fprintf(p.w, string(append(spec, 'd')), p.integer(p.next()))
+	case 'o', 'u', 'x', 'X':
+		if verb == 'u' {
+			verb = 'd'
+		}
+		v := uint64(p.integer(p.next()))
+		fmt.Fprintf(p.w, string(append(spec, verb)), v)
+	case 'c':
+		if arg := p.next(); len(arg) > 0 {
+			fmt.Fprintf(p.w, string(append(spec, 's')), arg[:1])
+		} else {
+			fmt.Fprintf(p.w, string(append(spec, 's')), "")
+		}
+	case 's':
+		fmt.Fprintf(p.w, string(append(spec, 's')), p.next())
+	case 'b':
+		arg := p.next()
+		var b strings.Builder
+		saved := p.w
+		p.w = bufio.NewWriter(&b)
+		stop := false
+		for j := 0; j < len(arg) && !stop; j++ {
+			if arg[j] != '\\' {
+				p.w.WriteByte(arg[j])
+				continue
+			}
+			var n int
+			n, stop = p.escape(arg[j+1:], true)
+			j += n
+		}
+		p.w.Flush()
+		p.w = saved
+		fmt.Fprintf(p.w, string(append(spec, 's')), b.String())
+		if stop {
+			return i, true
+		}
+	case 'e', 'E', 'f', 'F', 'g', 'G':
+		if verb == 'F' {
+			verb = 'f'
+		}
+		if (verb == 'g' || verb == 'G') &&
+			!strings.ContainsRune(string(spec), '.') {
+			// unlike fmt, C has a default precision of 6
+			spec = append(spec, ".6"...)
+		}
+		fmt.Fprintf(p.w, string(append(spec, verb)), p.float(p.next()))
+	default:
+		fmt.Fprintf(os.Stderr, "printf: %%%c: invalid conversion\n", verb)
+		p.failed = true
+	}
+	return i, false
+}
+
+func (p *printer) next() string {
+	if len(p.args) == 0 {
+		return ""
+	}
+	arg := p.args[0]
+	p.args = p.args[1:]
+	return arg
+}
+
+// integer returns the value of the argument, which may be octal with a
+// leading 0, hexadecimal with 0x, or the character after a quote.
+func (p *printer) integer(arg string) int64 {
+	s := strings.TrimSpace(arg)
+	if len(s) == 0 {
+		return 0
+	}
+	if s[0] == '\'' || s[0] == '"' {
+		if len(s) == 1 {
+			return 0
+		}
+		return int64([]rune(s[1:])[0])
+	}
+	v, err := strconv.ParseInt(s, 0, 64)
+	if err != nil {
+		u, uerr := strconv.ParseUint(s, 0, 64)
+		if uerr != nil {
+			fmt.Fprintf(os.Stderr, "printf: %s: invalid number\n", arg)
+			p.failed = true
+			return 0
+		}
+		v = int64(u)
+	}
+	return v
+}
+
+func (p *printer) float(arg string) float64 {
+	s := strings.TrimSpace(arg)
+	if len(s) == 0 {
+		return 0
+	}
+	if s[0] == '\'' || s[0] == '"' {
+		return float64(p.integer(s))
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		if i, ierr := strconv.ParseInt(s, 0, 64); ierr == nil {
+			return float64(i)
+		}
+		fmt.Fprintf(os.Stderr, "printf: %s: invalid number\n", arg)
+		p.failed = true
+		return 0
+	}
+	return v
+}
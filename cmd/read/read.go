@@ -0,0 +1,341 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package read
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/mattn/go-isatty"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/internal/flags"
+	"github.com/platinasystems/goes/internal/parms"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "read" }
+
+func (*Command) Usage() string {
+	return "read [-p PROMPT] [-t TIMEOUT] [-s] [-r] [VAR]..."
+}
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "read a line of input into variables",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Read a line from standard input and split it into fields with the
+	characters of $IFS, by default space, tab, and newline. Each VAR is
+	set to the next field and the last VAR to the rest of the line.
+	Without VAR, the whole line is set to REPLY.
+
+	A run of space, tab, or newline within $IFS separates fields as one
+	and is trimmed from the beginning and end of the line. Each other
+	character of $IFS separates two fields. An empty $IFS doesn't split.
+
+	Unless -r, a backslash quotes the next character and a backslash at
+	the end of line continues it with the next.
+
+	When the script itself is read from standard input, e.g.
+	"goes - < SCRIPT", read takes the next line of the script.
+
+OPTIONS
+	-p PROMPT	print PROMPT to standard error first
+	-t TIMEOUT	fail if the line isn't complete after TIMEOUT seconds
+	-s		don't echo the input of a terminal
+	-r		backslash isn't an escape
+
+EXIT STATUS
+	1 at the end of input, or greater than 128 after a timeout.
+
+EXAMPLES
+	read -p "Continue? " answer
+	cat /etc/hosts | while read addr names; do echo $names; done
+	IFS=: read user pw uid gid rest < /etc/passwd`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork }
+
+func (c *Command) Main(args ...string) error {
+	parm, args := parms.New(args, "-p", "-t")
+	flag, args := flags.New(args, "-s", "-r")
+	if len(args) == 0 {
+		args = []string{"REPLY"}
+	}
+	for _, name := range args {
+		if !isName(name) {
+			return fmt.Errorf("%s: invalid variable name", name)
+		}
+	}
+	var timeout time.Duration
+	if s := parm.ByName["-t"]; len(s) > 0 {
+		t, err := strconv.ParseFloat(s, 64)
+		if err != nil || t < 0 {
+			return fmt.Errorf("%s: invalid timeout", s)
+		}
+		timeout = time.Duration(t * float64(time.Second))
+	}
+	if s := parm.ByName["-p"]; len(s) > 0 {
+		fmt.Fprint(os.Stderr, s)
+	}
+	if flag.ByName["-s"] {
+		restore, err := noecho(os.Stdin)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			restore()
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+	escape := !flag.ByName["-r"]
+	var (
+		line string
+		err  error
+	)
+	if c.g.CatlineStdin && c.g.Catline != nil && os.Stdin.Fd() == 0 {
+		line, err = c.catline(escape)
+	} else {
+		line, err = readline(os.Stdin, timeout, escape)
+	}
+	if err == errTimeout {
+		return goes.ExitStatus(128 + int(syscall.SIGALRM))
+	}
+	if err != nil && (err != io.EOF || len(line) == 0) {
+		for _, name := range args {
+			c.g.Setenv(name, "")
+		}
+		if err == io.EOF {
+			err = goes.ExitStatus(1)
+		}
+		return err
+	}
+	ifs, set := c.g.LookupEnv("IFS")
+	if !set {
+		ifs = " \t\n"
+	}
+	fields := split(line, ifs, len(args), escape)
+	for i, name := range args {
+		v := ""
+		if i < len(fields) {
+			v = fields[i]
+		}
+		c.g.Setenv(name, v)
+	}
+	if err == io.EOF {
+		// like sh, the unterminated last line is set but fails
+		return goes.ExitStatus(1)
+	}
+	return nil
+}
+
+// catline reads the next line of the script that shares our standard input.
+func (c *Command) catline(escape bool) (string, error) {
+	line := ""
+	for {
+		s, err := c.g.Catline("")
+		if err != nil {
+			return line, err
+		}
+		c.g.Line++
+		line += s
+		if !escape || !continued(line) {
+			return line, nil
+		}
+		line = line[:len(line)-1]
+	}
+}
+
+var errTimeout = errors.New("timeout")
+
+// readline reads a byte at a time so that the rest of the input remains
+// for the next command, e.g. the next read of a while loop.
+func readline(f *os.File, timeout time.Duration, escape bool) (string, error) {
+	var (
+		line     []byte
+		deadline time.Time
+		b        [1]byte
+	)
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	for {
+		if timeout > 0 {
+			if err := wait(f, time.Until(deadline)); err != nil {
+				return "", err
+			}
+		}
+		n, err := f.Read(b[:])
+		if n == 0 {
+			if err == nil {
+				err = io.EOF
+			}
+			return string(line), err
+		}
+		if b[0] != '\n' {
+			line = append(line, b[0])
+		} else if escape && continued(string(line)) {
+			line = line[:len(line)-1]
+		} else {
+			return string(line), nil
+		}
+	}
+}
+
+// continued returns true if the line ends with an unescaped backslash.
+func continued(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// split returns at most n fields of the line separated by the characters of
+// ifs; the last has the rest of the line less any trailing ifs white space.
+// With escape, a backslash quotes the next character and is removed.
+func split(line, ifs string, n int, escape bool) []string {
+	var quoted []bool
+	if escape && strings.ContainsRune(line, '\\') {
+		b := make([]byte, 0, len(line))
+		for i := 0; i < len(line); i++ {
+			q := line[i] == '\\' && i+1 < len(line)
+			if q {
+				i++
+			}
+			b = append(b, line[i])
+			quoted = append(quoted, q)
+		}
+		line = string(b)
+	}
+	delim := func(i int) bool {
+		return (quoted == nil || !quoted[i]) &&
+			strings.IndexByte(ifs, line[i]) >= 0
+	}
+	white := func(i int) bool {
+		return delim(i) && strings.IndexByte(" \t\n", line[i]) >= 0
+	}
+	if len(ifs) == 0 {
+		return []string{line}
+	}
+	i, end := 0, len(line)
+	for i < end && white(i) {
+		i++
+	}
+	for end > i && white(end-1) {
+		end--
+	}
+	var fields []string
+	for i < end {
+		if len(fields) == n-1 {
+			fields = append(fields, line[i:end])
+			break
+		}
+		j := i
+		for j < end && !delim(j) {
+			j++
+		}
+		fields = append(fields, line[i:j])
+		// the separator is a run of white space with at most one
+		// other ifs character
+		for j < end && white(j) {
+			j++
+		}
+		if j < end && delim(j) && !white(j) {
+			for j++; j < end && white(j); j++ {
+			}
+		}
+		i = j
+	}
+	return fields
+}
+
+func isName(s string) bool {
+	for i, c := range s {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case i > 0 && c >= '0' && c <= '9':
+		default:
+			return false
+		}
+	}
+	return len(s) > 0
+}
+
+// wait returns errTimeout unless the file is readable within the timeout.
+func wait(f *os.File, timeout time.Duration) error {
+	if timeout <= 0 {
+		return errTimeout
+	}
+	fd := int(f.Fd())
+	for {
+		var set syscall.FdSet
+		bits := int(unsafe.Sizeof(set.Bits[0]) * 8)
+		set.Bits[fd/bits] |= 1 << uint(fd%bits)
+		tv := syscall.NsecToTimeval(timeout.Nanoseconds())
+		start := time.Now()
+		n, err := syscall.Select(fd+1, &set, nil, nil, &tv)
+		if err == syscall.EINTR {
+			timeout -= time.Since(start)
+			if timeout <= 0 {
+				return errTimeout
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errTimeout
+		}
+		return nil
+	}
+}
+
+// noecho turns off the echo of a terminal until restored.
+func noecho(f *os.File) (func(), error) {
+	fd := f.Fd()
+	if !isatty.IsTerminal(fd) {
+		return func() {}, nil
+	}
+	var t syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd,
+		uintptr(syscall.TCGETS), uintptr(unsafe.Pointer(&t)))
+	if errno != 0 {
+		return nil, fmt.Errorf("TCGETS: %v", errno)
+	}
+	saved := t
+	t.Lflag &^= syscall.ECHO
+	_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd,
+		uintptr(syscall.TCSETS), uintptr(unsafe.Pointer(&t)))
+	if errno != 0 {
+		return nil, fmt.Errorf("TCSETS: %v", errno)
+	}
+	return func() {
+		syscall.Syscall(syscall.SYS_IOCTL, fd,
+			uintptr(syscall.TCSETS), uintptr(unsafe.Pointer(&saved)))
+	}, nil
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/platinasystems/atsock"
 	grs "github.com/platinasystems/go-redis-server"
 	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/cmd/daemons"
 	"github.com/platinasystems/goes/internal/cmdline"
 	"github.com/platinasystems/goes/internal/fields"
 	"github.com/platinasystems/goes/internal/parms"
@@ -189,6 +190,9 @@ func (c *Command) Main(args ...string) (err error) {
 	if err != nil {
 		return
 	}
+	// for those that start After or Requires redisd; this fails if not
+	// run by goes-daemons
+	daemons.Ready()
 
 	go func(redisd *Redisd, args ...string) {
 		redisd.listen(args...)
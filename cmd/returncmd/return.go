@@ -0,0 +1,63 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package returncmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "return" }
+
+func (*Command) Usage() string { return "return [N]" }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "return from a function",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Return from the running function with the status N, or that of the
+	last command, $?, if not given.`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork | cmd.CantPipe }
+
+func (c *Command) Main(args ...string) error {
+	n := goes.ExitCode(c.g.Status)
+	switch len(args) {
+	case 0:
+	case 1:
+		i64, err := strconv.ParseInt(args[0], 0, 0)
+		if err != nil {
+			return err
+		}
+		n = int(i64) & 0xff
+	default:
+		return fmt.Errorf("%v: unexpected", args[1:])
+	}
+	if err := c.g.Return(); err != nil {
+		return err
+	}
+	if n != 0 {
+		return goes.ExitStatus(n)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package set
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/lang"
+)
+
+// flags are the single letter equivalents of the named options.
+var flags = map[rune]string{
+	'e': "errexit",
+	'u': "nounset",
+	'x': "xtrace",
+}
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "set" }
+
+func (*Command) Usage() string {
+	return "set [-eux] [+eux] [-o [NAME]] [+o [NAME]] [--] [ARG]..."
+}
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "set shell options and positional parameters",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Turn on the '-' prefaced, or off the '+' prefaced, shell options.
+	These are the options and their NAME for use with -o.
+
+	-e	errexit
+		Exit the shell, or script, if a command list fails; but not
+		with the failure of a command before '&&' or '||', or of an
+		if, while, or until condition.
+
+	-u	nounset
+		The expansion of an unset variable is an error, except with
+		${NAME:-WORD}, ${NAME:=WORD}, ${NAME:?WORD}, or ${NAME:+WORD}.
+
+	-x	xtrace
+		Print each command to stderr, preceded by '+', after its
+		expansion. This is the same as 'cli -x'.
+
+	-o pipefail
+		The status of a pipeline is that of its last command to fail,
+		rather than that of its last command. PIPESTATUS has the
+		status of each command regardless.
+
+	Functions and sourced scripts share the options of their caller.
+
+	With '-o' and no NAME, print the state of each option. With '+o',
+	print the set commands that restore the options.
+
+	Any ARG replace the positional parameters, as do none after '--'.
+	Without arguments, print the shell variables.
+
+EXAMPLES
+	set -eu -o pipefail
+	set -- $(cat /proc/loadavg)`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind {
+	return cmd.DontFork | cmd.CantPipe | cmd.NoCLIFlags
+}
+
+func (c *Command) Main(args ...string) error {
+	if len(args) == 0 {
+		c.printVariables()
+		return nil
+	}
+	params := false
+	for len(args) > 0 {
+		arg := args[0]
+		if arg == "--" {
+			args = args[1:]
+			params = true
+			break
+		}
+		if len(arg) < 2 || (arg[0] != '-' && arg[0] != '+') {
+			break
+		}
+		args = args[1:]
+		on := arg[0] == '-'
+		for _, r := range arg[1:] {
+			name, found := flags[r]
+			if r == 'o' {
+				if len(args) == 0 {
+					return c.printOptions(on)
+				}
+				name, found = args[0], true
+				args = args[1:]
+			}
+			if !found {
+				return fmt.Errorf("%c%c: invalid option", arg[0], r)
+			}
+			if err := c.g.SetOption(name, on); err != nil {
+				return err
+			}
+		}
+	}
+	if params || len(args) > 0 {
+		arg0 := c.g.Getenv("0")
+		c.g.SetParams(append([]string{arg0}, args...)...)
+	}
+	return nil
+}
+
+func (c *Command) printOptions(on bool) error {
+	for _, name := range goes.OptionNames() {
+		v, err := c.g.Option(name)
+		if err != nil {
+			return err
+		}
+		switch {
+		case on && v:
+			fmt.Printf("%-15s\ton\n", name)
+		case on:
+			fmt.Printf("%-15s\toff\n", name)
+		case v:
+			fmt.Println("set -o", name)
+		default:
+			fmt.Println("set +o", name)
+		}
+	}
+	return nil
+}
+
+func (c *Command) printVariables() {
+	names := make([]string, 0, len(c.g.EnvMap))
+	for name := range c.g.EnvMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s=%s\n", name, c.g.EnvMap[name])
+	}
+}
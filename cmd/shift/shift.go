@@ -0,0 +1,63 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package shift
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "shift" }
+
+func (*Command) Usage() string { return "shift [N]" }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "shift positional parameters",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Rename the positional parameters $N+1... to $1...; N defaults to 1.
+	It's an error if N is greater than $#.
+
+EXAMPLES
+	while [ $# -gt 0 ]; do
+		echo $1
+		shift
+	done`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork | cmd.CantPipe }
+
+func (c *Command) Main(args ...string) error {
+	n := 1
+	switch len(args) {
+	case 0:
+	case 1:
+		i64, err := strconv.ParseInt(args[0], 0, 0)
+		if err != nil {
+			return err
+		}
+		n = int(i64)
+	default:
+		return fmt.Errorf("%v: unexpected", args[1:])
+	}
+	return c.g.Shift(n)
+}
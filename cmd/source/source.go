@@ -20,7 +20,7 @@ type Command struct {
 func (*Command) String() string { return "source" }
 
 func (*Command) Usage() string {
-	return "source [-x] FILE"
+	return "source [-x] FILE [ARG]..."
 }
 
 func (*Command) Apropos() lang.Alt {
@@ -33,7 +33,8 @@ func (*Command) Man() lang.Alt {
 	return lang.Alt{
 		lang.EnUS: `
 DESCRIPTION
-	This is equivalent to 'cli [-x] URL'.`,
+	This is equivalent to 'cli [-x] URL [ARG]...'. Without ARGs, the
+	script has the positional parameters of the caller.`,
 	}
 }
 
@@ -46,14 +47,15 @@ func (c *Command) Main(args ...string) error {
 	if len(args) == 0 {
 		return fmt.Errorf("FILE: missing")
 	}
-	if len(args) > 1 {
-		return fmt.Errorf("%v: unexpected", args[1:])
+	params := args[1:]
+	if len(params) == 0 {
+		params = c.g.Params()
 	}
 	if flag.ByName["-x"] {
 		args = []string{"cli", "-x", args[0]}
 	} else {
 		args = []string{"cli", args[0]}
 	}
-	c.g.Catline = nil // Reset the input source
+	args = append(args, params...)
 	return c.g.Main(args...)
 }
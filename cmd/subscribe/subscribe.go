@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	redigo "github.com/garyburd/redigo/redis"
+	"github.com/platinasystems/goes/internal/rediscomplete"
 	"github.com/platinasystems/goes/lang"
 	"github.com/platinasystems/redis"
 )
@@ -24,6 +25,10 @@ func (Command) Apropos() lang.Alt {
 	}
 }
 
+func (Command) Complete(args ...string) []string {
+	return rediscomplete.Keys(args...)
+}
+
 func (Command) Main(args ...string) error {
 	switch len(args) {
 	case 0:
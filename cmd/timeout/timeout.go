@@ -0,0 +1,206 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package timeout
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/internal/duration"
+	"github.com/platinasystems/goes/lang"
+)
+
+// timedOut is the exit status of a command that timed out.
+const timedOut = 124
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "timeout" }
+
+func (*Command) Usage() string {
+	return "timeout [-s SIGNAL] [-k KILL_AFTER] DURATION COMMAND [ARG]..."
+}
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "run a command with a time limit",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Run the goes or external COMMAND and, if it's still running after
+	DURATION, send it SIGNAL, by default TERM.
+
+	The COMMAND is run in its own process group, with the default action
+	of SIGNAL, so that it's sent to the COMMAND and all of its children.
+	Those of INT, QUIT, HUP, and TERM received by timeout, unless
+	ignored, are also sent to the group.
+
+	DURATION and KILL_AFTER are a number of seconds, which may have a
+	fraction and a unit suffix of s, m, h, or d, e.g. 2.5 or 1m; or a
+	Go duration, e.g. 1m30s or 250ms. A DURATION of 0 doesn't time out.
+
+OPTIONS
+	-s SIGNAL	the signal to send at the DURATION, by name, e.g.
+			INT or SIGINT, or number
+	-k KILL_AFTER	also send KILL if the COMMAND is still running this
+			long after the first signal
+
+EXIT STATUS
+	124 if the COMMAND timed out, or 137 if it was sent KILL;
+	otherwise that of the COMMAND.
+
+EXAMPLES
+	timeout 5 ping 10.0.0.1
+	timeout -k 1 500ms i2c 0.76.0 b
+	timeout -s INT 1m hwait platina redis.ready true 2m`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork }
+
+func (c *Command) Main(args ...string) error {
+	var (
+		sig       = syscall.SIGTERM
+		killAfter time.Duration
+		err       error
+	)
+	// just the leading options; the rest are the COMMAND's
+	for len(args) > 1 && strings.HasPrefix(args[0], "-") {
+		opt := args[0]
+		if opt == "--" {
+			args = args[1:]
+			break
+		}
+		switch opt {
+		case "-s":
+			sig, err = goes.Signal(args[1])
+		case "-k":
+			killAfter, err = duration.Parse(args[1])
+		default:
+			return fmt.Errorf("%s: unknown", opt)
+		}
+		if err != nil {
+			return err
+		}
+		args = args[2:]
+	}
+	switch len(args) {
+	case 0:
+		return fmt.Errorf("DURATION: missing")
+	case 1:
+		return fmt.Errorf("COMMAND: missing")
+	}
+	d, err := duration.Parse(args[0])
+	if err != nil {
+		return err
+	}
+	// like sh, 127 if the COMMAND isn't found or 126 if it can't start
+	x, err := c.g.Command(args[1:]...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "timeout:", err)
+		return goes.ExitStatus(goes.ExitCode(err))
+	}
+	x.Stdin = os.Stdin
+	x.Stdout = os.Stdout
+	x.Stderr = os.Stderr
+	x.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err = start(x, sig); err != nil {
+		fmt.Fprintf(os.Stderr, "timeout: %s: %v\n", args[1], err)
+		return goes.ExitStatus(126)
+	}
+	// the process group of the COMMAND and its children
+	group := -x.Process.Pid
+	done := make(chan error, 1)
+	go func() { done <- x.Wait() }()
+	// forward those that the shell doesn't ignore
+	received := make(chan os.Signal, 1)
+	for _, s := range []syscall.Signal{
+		syscall.SIGINT,
+		syscall.SIGQUIT,
+		syscall.SIGHUP,
+		syscall.SIGTERM,
+	} {
+		if !signal.Ignored(s) {
+			signal.Notify(received, s)
+		}
+	}
+	defer signal.Stop(received)
+	var deadline <-chan time.Time
+	if d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		deadline = t.C
+	}
+	for deadline != nil {
+		select {
+		case err = <-done:
+			return status(err)
+		case s := <-received:
+			syscall.Kill(group, s.(syscall.Signal))
+		case <-deadline:
+			deadline = nil
+		}
+	}
+	syscall.Kill(group, sig)
+	if sig == syscall.SIGKILL {
+		<-done
+		return goes.ExitStatus(128 + int(syscall.SIGKILL))
+	}
+	var kill <-chan time.Time
+	if killAfter > 0 {
+		t := time.NewTimer(killAfter)
+		defer t.Stop()
+		kill = t.C
+	}
+	for {
+		select {
+		case <-done:
+			return goes.ExitStatus(timedOut)
+		case s := <-received:
+			syscall.Kill(group, s.(syscall.Signal))
+		case <-kill:
+			syscall.Kill(group, syscall.SIGKILL)
+			<-done
+			return goes.ExitStatus(128 + int(syscall.SIGKILL))
+		}
+	}
+}
+
+// start the COMMAND with the default action of the signal that it may be
+// sent, even if the shell ignores it, e.g. by a trap with an empty list.
+// The action of a caught signal is reset to the default by exec whereas
+// that of an ignored one isn't.
+func start(x *exec.Cmd, sig syscall.Signal) error {
+	if !signal.Ignored(sig) {
+		return x.Start()
+	}
+	signal.Notify(make(chan os.Signal, 1), sig)
+	defer signal.Ignore(sig)
+	return x.Start()
+}
+
+// status returns the quiet exit status of the COMMAND that already reported
+// any error itself.
+func status(err error) error {
+	if err == nil {
+		return nil
+	}
+	return goes.ExitStatus(goes.ExitCode(err))
+}
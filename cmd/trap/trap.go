@@ -0,0 +1,112 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package trap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/internal/flags"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "trap" }
+
+func (*Command) Usage() string {
+	return "trap [-lp] [[LIST] NAME...]"
+}
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "run commands on signals and shell exit",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Run the command LIST on receipt of each named signal, e.g. INT, SIGINT,
+	or 2; or on these conditions:
+
+	EXIT	the shell exits, including with 'exit' and 'set -e'
+	ERR	a command list fails where it would exit with 'set -e'
+
+	A trapped signal runs its LIST after the running command finishes;
+	whereas 'wait' returns immediately, with a status of 128 plus the
+	signal number, after running the LIST. The LIST doesn't change the
+	status, $?, of the last command.
+
+	An empty LIST ignores the signals; a LIST of '-', or none with one
+	NAME, restores their default action.
+
+	Without arguments, or with '-p', print the trap commands of the
+	trapped, or named, conditions. With '-l', print the condition names.
+
+EXAMPLES
+	trap 'umount /mnt/boot' EXIT
+	trap 'echo interrupted; exit 1' INT TERM HUP`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork | cmd.CantPipe }
+
+func (c *Command) Main(args ...string) error {
+	flag, args := flags.New(args, "-l", "-p")
+	switch {
+	case flag.ByName["-l"]:
+		if len(args) > 0 {
+			return fmt.Errorf("%v: unexpected", args)
+		}
+		for _, name := range goes.TrapNames() {
+			fmt.Println(name)
+		}
+		return nil
+	case flag.ByName["-p"] || len(args) == 0:
+		return c.print(args)
+	case len(args) == 1:
+		return c.g.Untrap(args[0])
+	}
+	list, names := args[0], args[1:]
+	for _, name := range names {
+		var err error
+		if list == "-" {
+			err = c.g.Untrap(name)
+		} else {
+			err = c.g.Trap(list, name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// print the trap command of the named, or all trapped, conditions.
+func (c *Command) print(names []string) error {
+	traps := c.g.Traps()
+	if len(names) == 0 {
+		names = goes.TrapNames()
+	}
+	for _, name := range names {
+		name, err := goes.TrapName(name)
+		if err != nil {
+			return err
+		}
+		if list, found := traps[name]; found {
+			fmt.Printf("trap -- '%s' %s\n",
+				strings.Replace(list, "'", `'\''`, -1), name)
+		}
+	}
+	return nil
+}
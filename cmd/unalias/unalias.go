@@ -0,0 +1,68 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package unalias
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/internal/flags"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "unalias" }
+
+func (*Command) Usage() string { return "unalias -a | NAME..." }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "remove command aliases",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Remove each named alias; or, with '-a', all aliases.
+
+SEE ALSO
+	alias`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork | cmd.CantPipe }
+
+func (c *Command) Main(args ...string) error {
+	flag, args := flags.New(args, "-a")
+	if flag.ByName["-a"] {
+		for name := range c.g.Aliases() {
+			c.g.Unalias(name)
+		}
+		return nil
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("NAME: missing")
+	}
+	var failed bool
+	for _, name := range args {
+		if err := c.g.Unalias(name); err != nil {
+			fmt.Fprintln(os.Stderr, "unalias:", err)
+			failed = true
+		}
+	}
+	if failed {
+		return goes.ExitStatus(1)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package unset
+
+import (
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/internal/flags"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "unset" }
+
+func (*Command) Usage() string { return "unset [-f | -v] NAME..." }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "remove variables or functions",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Remove each named variable from the shell and process environment;
+	or, with '-f', each named function.
+
+OPTIONS
+	-f	remove functions
+	-v	remove variables, the default`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind {
+	return cmd.DontFork | cmd.CantPipe | cmd.NoCLIFlags
+}
+
+func (c *Command) Main(args ...string) error {
+	flag, args := flags.New(args, "-f", "-v")
+	for _, name := range args {
+		if flag.ByName["-f"] {
+			delete(c.g.FunctionMap, name)
+		} else if err := c.g.Unsetenv(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
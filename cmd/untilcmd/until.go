@@ -0,0 +1,54 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package untilcmd
+
+import (
+	"errors"
+	"io"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd/whilecmd"
+	"github.com/platinasystems/goes/internal/shellutils"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct{}
+
+func (Command) String() string { return "until" }
+
+func (Command) Usage() string {
+	return "until COMMAND ; do COMMAND ; done"
+}
+
+func (Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "repeat commands until a condition is true",
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Repeatedly executes the commands following 'do' for as long as the
+	last command preceding 'do' fails.
+
+	The 'break' and 'continue' commands may be used to exit the loop or
+	skip to its next pass.
+
+EXAMPLES
+	until ping -c 1 -w 1 server ; do
+		sleep 1
+	done`,
+	}
+}
+
+func (Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func(stdin io.Reader, stdout io.Writer, stderr io.Writer, isFirst bool, isLast bool) error, error) {
+	return whilecmd.Loop(g, ls, true)
+}
+
+func (Command) Main(args ...string) error {
+	return errors.New("internal error")
+}
@@ -0,0 +1,64 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package wait
+
+import (
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "wait" }
+
+func (*Command) Usage() string { return "wait [%N | PID]..." }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "wait for background jobs to finish",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Wait for each given job, or all background jobs if none are given, to
+	finish. The exit status is that of the last job waited.
+
+	A job is specified by its number, preceded by '%', or the id of any
+	of its processes, e.g. $!.
+
+	%N	job number N
+	%%	the most recent job
+	%PREFIX	the most recent job with a command beginning with PREFIX
+	PID	the job that forked process PID`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork | cmd.CantPipe }
+
+func (c *Command) Main(args ...string) error {
+	var err error
+	if len(args) == 0 {
+		for _, j := range c.g.Jobs() {
+			err = c.g.WaitJob(j)
+		}
+		return err
+	}
+	for _, arg := range args {
+		j, t := c.g.FindJob(arg)
+		if t != nil {
+			return t
+		}
+		err = c.g.WaitJob(j)
+	}
+	return err
+}
@@ -23,6 +23,13 @@ import (
 type Command struct {
 	GpioPin string
 	Init    func()
+	// Healthy, if set, must return nil for each write, after the grace
+	// period of -g, so that the machine reboots if it remains unhealthy,
+	// e.g.
+	//	Healthy: daemons.Healthy
+	// That's also an error if goes-daemons is unreachable, so the machine
+	// reboots without its supervisor too.
+	Healthy func() error
 	init    sync.Once
 }
 
@@ -41,12 +48,17 @@ func (*Command) Man() lang.Alt {
 		lang.EnUS: `
 DESCRIPTION
 	Periodically write to the watchdog device (default /dev/watchdog).
+	A machine may skip the write while it's unhealthy, e.g. while any of
+	its critical daemons are unhealthy, so that it reboots.
 
 OPTIONS
 	-T TIMEOUT	Reboot after TIMEOUT seconds without a watchdog write
 			(default 60)
 	-t FREQUENCY	Write frequency in seconds
-			(default 30)`,
+			(default 30)
+	-g GRACE	Write, even if unhealthy, for GRACE seconds after
+			start while the machine's daemons start
+			(default 120)`,
 	}
 }
 
@@ -56,10 +68,11 @@ func (c *Command) Main(args ...string) error {
 	if c.Init != nil {
 		c.init.Do(c.Init)
 	}
-	parm, args := parms.New(args, "-T", "-t")
+	parm, args := parms.New(args, "-T", "-t", "-g")
 	for k, v := range map[string]string{
 		"-T": "60",
 		"-t": "30",
+		"-g": "120",
 	} {
 		if len(parm.ByName[k]) == 0 {
 			parm.ByName[k] = v
@@ -74,6 +87,12 @@ func (c *Command) Main(args ...string) error {
 	}
 	freq := time.Duration(period) * time.Second
 
+	grace, err := strconv.ParseInt(parm.ByName["-g"], 0, 0)
+	if err != nil || grace < 0 {
+		return fmt.Errorf("%s: invalid grace", parm.ByName["-g"])
+	}
+	endGrace := time.Now().Add(time.Duration(grace) * time.Second)
+
 	fn := "/dev/watchdog"
 	if n := len(args); n > 0 {
 		fn = args[0]
@@ -94,6 +113,14 @@ func (c *Command) Main(args ...string) error {
 	defer ticker.Stop()
 
 	for _ = range ticker.C {
+		if c.Healthy != nil {
+			if err = c.Healthy(); err != nil {
+				fmt.Fprintln(os.Stderr, "unhealthy:", err)
+				if time.Now().After(endGrace) {
+					continue
+				}
+			}
+		}
 		if len(c.GpioPin) > 0 {
 			pin, found := gpio.Pins[c.GpioPin]
 			t, err := pin.Value()
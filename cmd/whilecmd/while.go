@@ -0,0 +1,161 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package whilecmd
+
+import (
+	"errors"
+	"io"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/internal/shellutils"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct{}
+
+func (Command) String() string { return "while" }
+
+func (Command) Usage() string {
+	return "while COMMAND ; do COMMAND ; done"
+}
+
+func (Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "repeat commands while a condition is true",
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Repeatedly executes the commands following 'do' for as long as the
+	last command preceding 'do' is successful.
+
+	The 'break' and 'continue' commands may be used to exit the loop or
+	skip to its next pass.
+
+EXAMPLES
+	while [ -e /run/goes/install.lock ] ; do
+		sleep 1
+	done`,
+	}
+}
+
+func (Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func(stdin io.Reader, stdout io.Writer, stderr io.Writer, isFirst bool, isLast bool) error, error) {
+	return Loop(g, ls, false)
+}
+
+// Loop parses a while or until block with the given list beginning at the
+// keyword. The commands following 'do' are repeated until the condition
+// fails (while) or succeeds (until).
+func Loop(g *goes.Goes, ls shellutils.List, until bool) (*shellutils.List, func(stdin io.Reader, stdout io.Writer, stderr io.Writer, isFirst bool, isLast bool) error, error) {
+	var condList, doList []func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error
+	prompt := "while>"
+	if until {
+		prompt = "until>"
+	}
+	curList := &condList
+	pos := ls.Cmds[0].Cmds[0].Pos
+	// while <command>
+	ls = skipWord(ls)
+	for {
+		for len(ls.Cmds) == 0 {
+			newls, err := g.ParseBlock(prompt, pos, "done")
+			if err != nil {
+				return nil, nil, err
+			}
+			ls = *newls
+		}
+		cl := ls.Cmds[0]
+		name := ""
+		if len(cl.Cmds) > 0 {
+			name = cl.Cmds[0].String()
+		}
+		if name == "do" {
+			if curList != &condList || len(condList) == 0 {
+				return nil, nil, cl.Pos.Errorf("Unexpected 'do'")
+			}
+			curList = &doList
+			ls = skipWord(ls)
+			continue
+		}
+		if name == "done" {
+			if curList != &doList || len(doList) == 0 {
+				return nil, nil, cl.Pos.Errorf("Unexpected 'done'")
+			}
+			if !cl.IsRedirects(1) {
+				return nil, nil, cl.Cmds[1].Pos.Errorf(
+					"unexpected text after done")
+			}
+			break
+		}
+		nextls, _, runfun, err := g.ProcessList(ls)
+		if err != nil {
+			return nil, nil, err
+		}
+		*curList = append(*curList, runfun)
+		ls = *nextls
+	}
+	blockfun, err := makeBlockFunc(g, condList, doList, until)
+
+	return &ls, blockfun, err
+}
+
+// skipWord removes the keyword beginning the first command line of the list.
+func skipWord(ls shellutils.List) shellutils.List {
+	cl := ls.Cmds[0]
+	if len(cl.Cmds) > 1 {
+		cl.Cmds = cl.Cmds[1:]
+		ls.Cmds[0] = cl
+	} else {
+		ls.Cmds = ls.Cmds[1:]
+	}
+	return ls
+}
+
+func runList(pipeline []func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	for _, runent := range pipeline {
+		err := runent(stdin, stdout, stderr)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func makeBlockFunc(g *goes.Goes, condList, doList []func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error, until bool) (func(stdin io.Reader, stdout io.Writer, stderr io.Writer, isFirst bool, isLast bool) error, error) {
+	runfun := func(stdin io.Reader, stdout io.Writer, stderr io.Writer, isFirst bool, isLast bool) error {
+		var status error
+		g.EnterLoop()
+		defer g.ExitLoop()
+		for {
+			g.EnterCondition()
+			err := runList(condList, stdin, stdout, stderr)
+			g.ExitCondition()
+			if err != nil {
+				return err
+			}
+			if (g.Status == nil) == until {
+				break
+			}
+			err = runList(doList, stdin, stdout, stderr)
+			if err != nil {
+				return err
+			}
+			status = g.Status
+			if g.EndOfPass() {
+				break
+			}
+		}
+		g.Status = status
+		return nil
+	}
+	return runfun, nil
+}
+
+func (Command) Main(args ...string) error {
+	return errors.New("internal error")
+}
@@ -0,0 +1,78 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Cmdsub runs the command list of a $(...) or `...` substitution in a
+// subshell, a child copy of the shell state, and returns its output with
+// trailing newlines removed. Its status is that of the command, e.g. an
+// assignment, if it doesn't run any other.
+func (g *Goes) Cmdsub(s string) (string, error) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return "", err
+	}
+	output := make(chan []byte)
+	go func() {
+		b, _ := ioutil.ReadAll(pr)
+		pr.Close()
+		output <- b
+	}()
+	c := g.child(nil, g.Line, g.procs)
+	c.subshell = true
+	err = c.eval(s, pw)
+	// the subshell's in-process commands set the cli's shell to it
+	if cli, found := g.ByName["cli"].(goeser); found {
+		cli.Goes(g)
+	}
+	pw.Close()
+	b := <-output
+	g.cmdsub = c.Status
+	return strings.TrimRight(string(b), "\n"), err
+}
+
+// eval runs the command lines of s with the given stdout.
+func (g *Goes) eval(s string, stdout io.Writer) error {
+	lines := strings.Split(s, "\n")
+	catline, line := g.Catline, g.Line
+	defer func() { g.Catline, g.Line = catline, line }()
+	g.Line = 0
+	g.Catline = func(prompt string) (string, error) {
+		if len(lines) == 0 {
+			return "", io.EOF
+		}
+		line := lines[0]
+		lines = lines[1:]
+		return line, nil
+	}
+	for {
+		ls, err := g.Parse("")
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for len(ls.Cmds) != 0 {
+			newls, _, runner, err := g.ProcessList(*ls)
+			if err != nil {
+				return err
+			}
+			if err = runner(os.Stdin, stdout, os.Stderr); err != nil {
+				return err
+			}
+			if g.Exiting() {
+				return nil
+			}
+			ls = newls
+		}
+	}
+}
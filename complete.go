@@ -15,39 +15,48 @@ type completer interface {
 	Complete(...string) []string
 }
 
+// Complete returns the completions of the last of args. The first completes
+// to the name of a command or builtin; the rest, with the Complete method of
+// that command or else to file names. Since a nested Goes, like ip, has this
+// method, its subcommands complete the same way at every level. A leading
+// option that isn't a command, e.g. "ip -4 address", is skipped.
 func (g *Goes) Complete(args ...string) (completions []string) {
 	n := len(args)
-	if n == 0 || len(args[0]) == 0 {
-		completions = g.Names()
-	} else if v, found := g.ByName[args[0]]; found {
+	if n == 0 || n == 1 && len(args[0]) == 0 {
+		return g.Names()
+	}
+	if n == 1 {
+		completions = g.prefixed(args[0])
+		for builtin := range g.Builtins() {
+			if strings.HasPrefix(builtin, args[0]) {
+				completions = append(completions, builtin)
+			}
+		}
+		sort.Strings(completions)
+		return
+	}
+	if v, found := g.ByName[args[0]]; found {
 		if method, found := v.(completer); found {
 			completions = method.Complete(args[1:]...)
 		} else {
 			completions, _ = filepath.Glob(args[n-1] + "*")
 		}
 	} else if _, found := g.Builtins()[args[0]]; found {
-		if n == 1 || len(args[n-1]) == 0 {
-			completions = g.Names()
-		} else {
-			for _, name := range g.Names() {
-				if strings.HasPrefix(name, args[n-1]) {
-					completions = append(completions, name)
-				}
-			}
-		}
-	} else if n == 1 {
-		for _, name := range g.Names() {
-			if strings.HasPrefix(name, args[0]) {
-				completions = append(completions, name)
-			}
-		}
-		for builtin := range g.Builtins() {
-			if strings.HasPrefix(builtin, args[0]) {
-				completions = append(completions, builtin)
-			}
-		}
-		if len(completions) > 0 {
-			sort.Strings(completions)
+		completions = g.prefixed(args[n-1])
+	} else if strings.HasPrefix(args[0], "-") {
+		completions = g.Complete(args[1:]...)
+	} else {
+		// an external program
+		completions, _ = filepath.Glob(args[n-1] + "*")
+	}
+	return
+}
+
+// prefixed returns the sorted command names that begin with prefix.
+func (g *Goes) prefixed(prefix string) (names []string) {
+	for _, name := range g.Names() {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
 		}
 	}
 	return
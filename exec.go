@@ -0,0 +1,103 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// notFoundError is the error of a command that isn't a function, builtin,
+// or external program; it has the exit status 127.
+type notFoundError string
+
+func (s notFoundError) Error() string { return string(s) + ": command not found" }
+
+func (notFoundError) ExitCode() int { return 127 }
+
+// cantExecError is the error of an external program that fails to start;
+// it has the exit status 126.
+type cantExecError struct {
+	name string
+	err  error
+}
+
+func (e cantExecError) Error() string { return fmt.Sprint(e.name, ": ", e.err) }
+
+func (cantExecError) ExitCode() int { return 126 }
+
+// LookPath returns the file name of the named external program, searching
+// the directories of the PATH variable if the name doesn't have a slash.
+func (g *Goes) LookPath(name string) (string, error) {
+	if strings.Contains(name, "/") {
+		if _, err := os.Stat(name); err != nil {
+			return "", notFoundError(name)
+		}
+		if !isExecutable(name) {
+			return "", cantExecError{name, os.ErrPermission}
+		}
+		return name, nil
+	}
+	for _, dir := range filepath.SplitList(g.Getenv("PATH")) {
+		if len(dir) == 0 {
+			dir = "."
+		}
+		fn := filepath.Join(dir, name)
+		if isExecutable(fn) {
+			return fn, nil
+		}
+	}
+	return "", notFoundError(name)
+}
+
+func isExecutable(fn string) bool {
+	fi, err := os.Stat(fn)
+	return err == nil && !fi.IsDir() && fi.Mode()&0111 != 0
+}
+
+// Command returns the exec.Cmd of a goes command, forked with Fork, or else
+// the external program named by args[0], e.g. for commands like timeout that
+// run another.
+func (g *Goes) Command(args ...string) (*exec.Cmd, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("COMMAND: missing")
+	}
+	if _, found := g.ByName[args[0]]; found {
+		return g.Fork(args...), nil
+	}
+	return g.external(args, nil)
+}
+
+// external returns an exec.Cmd of the program named by args[0] with the
+// exported variables and those assigned by the command line.
+func (g *Goes) external(args []string, envMap map[string]string) (*exec.Cmd, error) {
+	fn, err := g.LookPath(args[0])
+	if err != nil {
+		return nil, err
+	}
+	x := exec.Command(fn, args[1:]...)
+	x.Args[0] = args[0]
+	x.Env = g.environ(envMap)
+	return x, nil
+}
+
+// environ returns the environment of a program: the exported variables,
+// i.e. those of the process environment with any values assigned since by
+// the shell, and those assigned by the command line.
+func (g *Goes) environ(envMap map[string]string) []string {
+	exported := make(map[string]string)
+	for k, v := range g.EnvMap {
+		if _, found := os.LookupEnv(k); found {
+			exported[k] = v
+		}
+	}
+	for k, v := range envMap {
+		exported[k] = v
+	}
+	return append(os.Environ(), sortedEnv(exported)...)
+}
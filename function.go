@@ -0,0 +1,98 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// MaxFunctionDepth is the limit of nested, e.g. recursive, function calls.
+const MaxFunctionDepth = 1000
+
+var (
+	errNotInFunction = errors.New("can only be used in a function")
+	errNotReturnable = errors.New("can only `return' from a function")
+)
+
+// functions records the scopes of the running function calls and any
+// return that is unwinding the innermost.
+type functions struct {
+	scopes    []scope
+	returning bool
+}
+
+// scope saves the values of the variables hidden by the local variables of
+// a function call; nil if the variable was unset.
+type scope map[string]*string
+
+// call runs the function with args as its positional parameters and a new
+// scope for local variables.
+func (g *Goes) call(f Function, args []string, stdin io.Reader, stdout, stderr io.Writer, isFirst, isLast bool) error {
+	if len(g.functions.scopes) >= MaxFunctionDepth {
+		return fmt.Errorf("%s: maximum function nesting level exceeded (%d)",
+			f.Name, MaxFunctionDepth)
+	}
+	arg0, _ := g.param("0")
+	saved := g.SetParams(append([]string{arg0}, args[1:]...)...)
+	g.functions.scopes = append(g.functions.scopes, scope{})
+	defer func() {
+		top := len(g.functions.scopes) - 1
+		for name, v := range g.functions.scopes[top] {
+			if v == nil {
+				delete(g.EnvMap, name)
+			} else {
+				g.Setenv(name, *v)
+			}
+		}
+		g.functions.scopes = g.functions.scopes[:top]
+		g.SetParams(saved...)
+	}()
+	err := f.RunFun(stdin, stdout, stderr, isFirst, isLast)
+	g.functions.returning = false
+	return err
+}
+
+// Local assigns a variable that is restored when the running function
+// returns.
+func (g *Goes) Local(name, value string) error {
+	n := len(g.functions.scopes)
+	if n == 0 {
+		return errNotInFunction
+	}
+	if _, found := g.functions.scopes[n-1][name]; !found {
+		if v, found := g.EnvMap[name]; found {
+			g.functions.scopes[n-1][name] = &v
+		} else {
+			g.functions.scopes[n-1][name] = nil
+		}
+	}
+	g.Setenv(name, value)
+	return nil
+}
+
+// Return from the running function; its status is that of the command
+// that calls Return.
+func (g *Goes) Return() error {
+	if len(g.functions.scopes) == 0 {
+		return errNotReturnable
+	}
+	g.functions.returning = true
+	return nil
+}
+
+// Returning returns true while a return is unwinding the running function.
+func (g *Goes) Returning() bool {
+	return g.functions.returning
+}
+
+// Unsetenv removes the named variable from the goes context and process
+// environment.
+func (g *Goes) Unsetenv(name string) error {
+	delete(g.EnvMap, name)
+	return os.Unsetenv(name)
+}
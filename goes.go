@@ -18,7 +18,10 @@ import (
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 	"unicode/utf8"
@@ -26,10 +29,8 @@ import (
 	"github.com/platinasystems/goes/cmd"
 	"github.com/platinasystems/goes/lang"
 	"github.com/platinasystems/goes/internal/flags"
-	"github.com/platinasystems/goes/internal/parms"
 	"github.com/platinasystems/goes/internal/prog"
 	"github.com/platinasystems/goes/internal/shellutils"
-	"github.com/platinasystems/goes/internal/url"
 )
 
 const (
@@ -57,12 +58,32 @@ type Goes struct {
 	ByName map[string]cmd.Cmd
 
 	Catline func(string) (string, error)
+	// CatlineStdin is true if Catline reads os.Stdin, e.g. with
+	// "goes - < SCRIPT", so commands like read share its input
+	CatlineStdin bool
+	// Line is the number of lines read with Catline
+	Line int
 
 	Status    error
 	Verbosity int
 
-	cache  cache
-	parent *Goes
+	aliases   map[string]string
+	args      []string
+	cache     cache
+	functions functions
+	jobs      jobs
+	loop      loop
+	options   options
+	parent    *Goes
+	procs     *jobProcs
+	stages    *stages
+	traps     traps
+	// subshell is true for the child of a command substitution, which
+	// exits without ending the shell
+	subshell bool
+	// cmdsub is the status of the last command substitution of the
+	// running command
+	cmdsub error
 
 	EnvMap map[string]string
 
@@ -82,7 +103,19 @@ func (g *Goes) ProcessPipeline(ls shellutils.List) (*shellutils.List, *shellutil
 	)
 	isLast := false
 	pipeline := make([]func(io.Reader, io.Writer, io.Writer, bool, bool) error, 0)
+	st := &stages{}
+	saved := g.stages
+	g.stages = st
+	defer func() { g.stages = saved }()
 	for len(ls.Cmds) != 0 && !isLast {
+		expanded, err := g.expandAlias(ls)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		ls = expanded
+		if len(ls.Cmds) == 0 {
+			break
+		}
 		cl := ls.Cmds[0]
 		term = cl.Term
 		if term.String() != "|" {
@@ -90,7 +123,10 @@ func (g *Goes) ProcessPipeline(ls shellutils.List) (*shellutils.List, *shellutil
 		}
 
 		var runfun func(stdin io.Reader, stdout io.Writer, stderr io.Writer, isFirst bool, isLast bool) error
-		name := cl.Cmds[0].String()
+		var name string
+		if len(cl.Cmds) > 0 {
+			name = cl.Cmds[0].String()
+		}
 		if v := g.ByName[name]; v != nil {
 			if method, found := v.(Blocker); found {
 				var (
@@ -109,16 +145,23 @@ func (g *Goes) ProcessPipeline(ls shellutils.List) (*shellutils.List, *shellutil
 				if term.String() != "|" {
 					isLast = true
 				}
+				// e.g. "done < FILE"
+				if len(cl.Cmds) > 1 {
+					runfun = g.redirectBlock(cl, runfun,
+						&closers)
+				}
 				pipeline = append(pipeline, runfun)
+				st.status = append(st.status, nil)
 				continue
 			}
 		}
-		runfun, err := g.ProcessCommand(cl, &closers)
+		runfun, err = g.ProcessCommand(cl, &closers)
 		if err != nil {
 			return nil, nil, nil, err
 		}
 		ls.Cmds = ls.Cmds[1:]
 		pipeline = append(pipeline, runfun)
+		st.status = append(st.status, nil)
 	}
 
 	pipefun, err := g.MakePipefun(pipeline, &closers)
@@ -126,14 +169,22 @@ func (g *Goes) ProcessPipeline(ls shellutils.List) (*shellutils.List, *shellutil
 }
 
 func (g *Goes) ProcessCommand(cl shellutils.Cmdline, closers *[]io.Closer) (func(stdin io.Reader, stdout io.Writer, stderr io.Writer, isFirst bool, isLast bool) error, error) {
-	runfun := func(stdin io.Reader, stdout io.Writer, stderr io.Writer, isFirst bool, isLast bool) error {
-		envMap, args := cl.Slice(func(k string) string {
-			v, def := g.EnvMap[k]
-			if def {
-				return v
-			}
-			return os.Getenv(k)
-		})
+	procs := g.procs
+	st := g.stages
+	stage := 0
+	if st != nil {
+		stage = len(st.status)
+	}
+	runfun := func(stdin io.Reader, stdout io.Writer, stderr io.Writer, isFirst bool, isLast bool) (err error) {
+		g.cmdsub = nil
+		envMap, args, redirects, err := cl.SliceRedirects(g)
+		if err != nil {
+			return err
+		}
+		if g.Verbosity >= VerboseVerify {
+			fmt.Fprintln(stderr, "+", strings.Join(append(sortedEnv(envMap),
+				args...), " "))
+		}
 		// Add to our context environment if this command only set variables
 		if len(args) == 0 {
 			if len(envMap) != 0 {
@@ -144,16 +195,33 @@ func (g *Goes) ProcessCommand(cl shellutils.Cmdline, closers *[]io.Closer) (func
 						g.EnvMap[k] = v
 					}
 				}
-				g.Status = nil // Successfully set variables
 			}
+			// that of the last command substitution, if any
+			g.Status = g.cmdsub
 			return nil
 		}
 		name := args[0]
+		in, out, errout, err := g.redirect(redirects, stdin, stdout,
+			stderr, closers)
+		if err != nil {
+			return err
+		}
+		// e.g. "command not found" of "nosuch 2>/dev/null"
+		defer func() {
+			if _, quiet := err.(ExitStatus); err != nil &&
+				!quiet && errout != stderr {
+				fmt.Fprintln(errout, err)
+				err = ExitStatus(ExitCode(err))
+			}
+		}()
+		redirected := len(redirects) > 0 || in != io.Reader(os.Stdin) ||
+			out != io.Writer(os.Stdout) || errout != io.Writer(os.Stderr)
 		// check for function invocation
 		if f, x := g.FunctionMap[name]; x {
-			return f.RunFun(stdin, stdout, stderr, isFirst, isLast)
+			return g.call(f, args, in, out, errout, isFirst, isLast)
 		}
-		// check for built in command
+		// check for built in command, then external program
+		var x *exec.Cmd
 		if v := g.ByName[name]; v != nil {
 			k := cmd.WhatKind(v)
 			if k.IsDaemon() {
@@ -166,140 +234,68 @@ func (g *Goes) ProcessCommand(cl shellutils.Cmdline, closers *[]io.Closer) (func
 					return fmt.Errorf(
 						"%s: can't pipe", name)
 				}
-			} else if k.IsDontFork() ||
-				name == os.Args[0] {
+			} else if (k.IsDontFork() && !procs.background()) ||
+				(name == os.Args[0] &&
+					!(redirected && procs.background())) {
 				if method, found := v.(goeser); found {
 					method.Goes(g)
 				}
-				return g.Main(args...)
-			}
-		} else if builtin, found := g.Builtins()[name]; found {
-			return builtin(args[1:]...)
-		} else {
-			return fmt.Errorf("%s: command not found", name)
-		}
-		in := stdin
-		if isFirst {
-			var iparm *parms.Parms
-			isFirst = false
-			iparm, args = parms.New(args, "<", "<<", "<<-")
-			if fn := iparm.ByName["<"]; len(fn) > 0 {
-				rc, err := url.Open(fn)
-				if err != nil {
-					return err
-				}
-				in = rc
-				*closers = append(*closers, rc)
-			} else if len(iparm.ByName["<<"]) > 0 ||
-				len(iparm.ByName["<<-"]) > 0 {
-				var trim bool
-				lbl := iparm.ByName["<<"]
-				if len(lbl) == 0 {
-					lbl = iparm.ByName["<<-"]
-					trim = true
+				if len(envMap) != 0 {
+					defer g.assign(envMap)()
 				}
-				r, w, err := os.Pipe()
-				if err != nil {
-					return err
+				// e.g. read in the block of a pipeline
+				if redirected {
+					return stdio(in, out, errout, func() error {
+						return g.Main(args...)
+					})
 				}
-				in = r
-				*closers = append(*closers, r)
-				go func(w io.WriteCloser, lbl string) {
-					defer w.Close()
-					prompt := "<<" + fn + " "
-					for {
-						s, err := g.Catline(prompt)
-						if err != nil || s == lbl {
-							break
-						}
-						if trim {
-							s = strings.TrimLeft(s, " \t")
-						}
-						fmt.Fprintln(w, s)
-					}
-				}(w, lbl)
-			}
-		}
-		out := stdout
-		if isLast {
-			var oparm *parms.Parms
-			oparm, args = parms.New(args, ">", ">>", ">>>", ">>>>")
-			if fn := oparm.ByName[">"]; len(fn) > 0 {
-				wc, err := url.Create(fn)
-				if err != nil {
-					return err
-				}
-				out = wc
-				*closers = append(*closers, wc)
-			} else if fn = oparm.ByName[">>"]; len(fn) > 0 {
-				wc, err := url.Append(fn)
-				if err != nil {
-					return err
-				}
-				out = wc
-				*closers = append(*closers, wc)
-			} else if fn := oparm.ByName[">>>"]; len(fn) > 0 {
-				wc, err := url.Create(fn)
-				if err != nil {
-					return err
-				}
-				out = io.MultiWriter(os.Stdout, wc)
-				*closers = append(*closers, wc)
-			} else if fn := oparm.ByName[">>"]; len(fn) > 0 {
-				wc, err := url.Append(fn)
-				if err != nil {
-					return err
-				}
-				out = io.MultiWriter(os.Stdout, wc)
-				*closers = append(*closers, wc)
+				return g.Main(args...)
 			}
-		}
-		var envStr []string
-		if len(envMap) != 0 {
-			envStr = make([]string, 0)
-			for k, v := range envMap {
-				envStr = append(envStr, fmt.Sprintf("%s=%s", k, v))
+		} else if builtin, found := g.Builtins()[name]; found {
+			if !redirected {
+				return builtin(args[1:]...)
+			} else if !procs.background() {
+				return stdio(in, out, errout, func() error {
+					return builtin(args[1:]...)
+				})
 			}
+			// a background job forks rather than replace the
+			// stdio of the foreground
+		} else if x, err = g.external(args, envMap); err != nil {
+			return err
 		}
-		if g.Verbosity >= VerboseVerify {
-			fmt.Println("+", strings.Join(envStr, " "), strings.Join(args, " "))
-		}
-		x := g.Fork(args...)
-		if len(envStr) != 0 {
-			x.Env = os.Environ()
-			for _, s := range envStr {
-				x.Env = append(x.Env, s)
-			}
+		program := x != nil
+		if !program {
+			x = g.Fork(args...)
+			x.Env = g.environ(envMap)
 		}
 		x.Stdin = in
 		x.Stdout = out
-		x.Stderr = stderr
+		x.Stderr = errout
+		if procs.background() {
+			// keep tty signals from the background job
+			x.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		}
 
 		if err := x.Start(); err != nil {
+			if program {
+				return cantExecError{name, err}
+			}
 			err = fmt.Errorf("child: %v: %v", x.Args, err)
 			return err
 		}
+		procs.add(x.Process)
 		if isLast {
 			err := x.Wait()
 			g.Status = err
 		} else {
+			st.start()
 			go func(x *exec.Cmd) {
 				err := x.Wait()
-				if err != nil {
-					fmt.Fprintln(os.Stderr, err)
-				}
-				if x.Stdout != os.Stdout {
-					m, found := x.Stdout.(io.Closer)
-					if found {
-						m.Close()
-					}
-				}
-				if x.Stdin != os.Stdin {
-					m, found := x.Stdin.(io.Closer)
-					if found {
-						m.Close()
-					}
+				if err != nil && !brokenPipe(err) {
+					fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
 				}
+				st.done(stage, err)
 			}(x)
 		}
 		return nil
@@ -308,39 +304,172 @@ func (g *Goes) ProcessCommand(cl shellutils.Cmdline, closers *[]io.Closer) (func
 }
 
 func (g *Goes) MakePipefun(pipeline []func(io.Reader, io.Writer, io.Writer, bool, bool) error, closers *[]io.Closer) (func(io.Reader, io.Writer, io.Writer) error, error) {
+	st := g.stages
 	pipefun := func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
 		var (
-			err error
-			pin *os.File
+			err       error
+			pin, pout *os.File
 		)
+		// close just those opened by this run, which may be nested
+		// in a recursive function call of the same pipeline
+		n := len(*closers)
 		defer func() {
-			for _, c := range *closers {
+			for _, c := range (*closers)[n:] {
 				c.Close()
 			}
+			*closers = (*closers)[:n]
 		}()
 		in := stdin
 		end := len(pipeline) - 1
-		for i, runfun := range pipeline {
+		i := 0
+		for ; i <= end; i++ {
 			out := stdout
 			if i != end {
-				var pout *os.File
 				pin, pout, err = os.Pipe()
 				if err != nil {
+					if i != 0 {
+						in.(*os.File).Close()
+					}
 					break
 				}
 				out = pout
 			}
-			err = runfun(in, out, stderr, i == 0, i == end)
+			err = pipeline[i](in, out, stderr, i == 0, i == end)
+			// the stage has either forked with its own copies of
+			// the pipes or is done with them; so, close those of
+			// the shell for the writer to see the reader quit and
+			// the reader to see the end of the writer
+			if i != end {
+				pout.Close()
+			}
+			if i != 0 {
+				in.(*os.File).Close()
+			}
+			// the reader of one that has reported its error runs
+			// anyway, e.g. "nosuch 2>&1 | cat"
+			if _, quiet := err.(ExitStatus); quiet && i != end &&
+				st != nil {
+				st.status[i] = err
+				err = nil
+			}
 			if err != nil {
+				if i != end {
+					pin.Close()
+				}
 				break
 			}
 			in = pin
 		}
+		if st == nil {
+			return err
+		}
+		st.Wait()
+		if err != nil {
+			st.status[i] = err
+		} else if end >= 0 {
+			st.status[end] = g.Status
+		}
+		codes := make([]string, len(st.status))
+		for i, status := range st.status {
+			codes[i] = strconv.Itoa(ExitCode(status))
+		}
+		g.Setenv("PIPESTATUS", strings.Join(codes, " "))
+		if err == nil && g.options.pipefail {
+			// the status is that of the last command to fail
+			for i := len(st.status) - 2; i >= 0 && g.Status == nil; i-- {
+				g.Status = st.status[i]
+			}
+		}
 		return err
 	}
 	return pipefun, nil
 }
 
+// stages records the exit status of the commands of a pipeline, those that
+// run in the background of its last command and, once that's done, its own,
+// for PIPESTATUS and pipefail.
+type stages struct {
+	sync.Mutex
+	sync.WaitGroup
+	status []error
+}
+
+func (st *stages) start() {
+	if st != nil {
+		st.Add(1)
+	}
+}
+
+func (st *stages) done(i int, err error) {
+	if st != nil {
+		st.Lock()
+		st.status[i] = err
+		st.Unlock()
+		st.Done()
+	}
+}
+
+// brokenPipe returns true if the error is that of a command killed by
+// SIGPIPE, the usual end of a stage whose reader quit, e.g. "cat FILE | head".
+func brokenPipe(err error) bool {
+	return ExitCode(err) == 128+int(syscall.SIGPIPE)
+}
+
+// sortedEnv returns the NAME=VALUE strings of the map sorted by name.
+func sortedEnv(m map[string]string) []string {
+	s := make([]string, 0, len(m))
+	for k, v := range m {
+		s = append(s, fmt.Sprint(k, "=", v))
+	}
+	sort.Strings(s)
+	return s
+}
+
+// Getenv returns the value of the named special or positional parameter,
+// or variable from the goes context or, if not set there, the process
+// environment.
+func (g *Goes) Getenv(k string) string {
+	if v, found := g.param(k); found {
+		return v
+	}
+	v, def := g.EnvMap[k]
+	if def {
+		return v
+	}
+	return os.Getenv(k)
+}
+
+// Setenv assigns the named variable in the goes context.
+func (g *Goes) Setenv(k, v string) {
+	if g.EnvMap == nil {
+		g.EnvMap = make(map[string]string)
+	}
+	g.EnvMap[k] = v
+}
+
+// assign sets the variables of an in-process command, e.g. "IFS=: read a b",
+// and returns the func that restores their prior values.
+func (g *Goes) assign(envMap map[string]string) func() {
+	saved := make(map[string]*string, len(envMap))
+	for k, v := range envMap {
+		if prior, def := g.EnvMap[k]; def {
+			saved[k] = &prior
+		} else {
+			saved[k] = nil
+		}
+		g.Setenv(k, v)
+	}
+	return func() {
+		for k, v := range saved {
+			if v != nil {
+				g.EnvMap[k] = *v
+			} else {
+				delete(g.EnvMap, k)
+			}
+		}
+	}
+}
+
 func Replace(s, name string) string {
 	return strings.Replace(s, "goes", name, -1)
 }
@@ -416,7 +545,16 @@ func (g *Goes) Main(args ...string) error {
 		if clifound {
 			cli.(goeser).Goes(g)
 		}
-		cliFlags, cliArgs := flags.New(args, "-d", "-f", "-no-liner", "-x")
+		// -n must precede the script since it's also an option of
+		// other commands, e.g. echo -n
+		noexec := len(args) > 1 && args[0] == "-n"
+		if noexec {
+			args = args[1:]
+		}
+		// a copy, since flags are removed in place from what may be
+		// a command's args, e.g. daemon log -f
+		cliFlags, cliArgs := flags.New(append([]string{}, args...),
+			"-d", "-f", "-no-liner", "-x")
 		if cliFlags.ByName["-d"] && g.Verbosity < VerboseDebug {
 			g.Verbosity = VerboseDebug
 		}
@@ -437,24 +575,43 @@ func (g *Goes) Main(args ...string) error {
 			fmt.Println(Usage(g))
 			g.Status = nil
 			return nil
-		} else if n == 1 && !found {
-			// only check for script if args[0] isn't a command
-			buf, err := ioutil.ReadFile(cliArgs[0])
-			if cliArgs[0] == "-" || (err == nil && utf8.Valid(buf) &&
-				bytes.HasPrefix(buf, []byte("#!/usr/bin/goes"))) {
-				// e.g. /usr/bin/goes SCRIPT
+		} else if !found {
+			// only check for script if args[0] isn't a command; "-"
+			// is that of stdin, e.g. goes - [ARG]... < SCRIPT
+			script := cliArgs[0] == "-"
+			if !script {
+				// -n checks any text file, e.g. one that's
+				// sourced rather than run, so without "#!"
+				buf, err := ioutil.ReadFile(cliArgs[0])
+				script = err == nil && utf8.Valid(buf) &&
+					(noexec || bytes.HasPrefix(buf,
+						[]byte("#!/usr/bin/goes")))
+			}
+			if script {
+				// e.g. /usr/bin/goes SCRIPT [ARG]...
 				if cli == nil {
 					g.Status = fmt.Errorf("has no cli")
 					return g.Status
 				}
+				var opts []string
 				for _, t := range []string{"-f", "-x"} {
 					if cliFlags.ByName[t] {
-						cliArgs = append(cliArgs, t)
+						opts = append(opts, t)
 					}
 				}
-				g.Status = cli.Main(cliArgs...)
+				if noexec {
+					opts = append(opts, "-n")
+				}
+				g.Status = cli.Main(append(opts, cliArgs...)...)
 				return g.Status
 			}
+			if noexec {
+				g.Status = fmt.Errorf("%s: not a script", cliArgs[0])
+				return g.Status
+			}
+			if n > 1 {
+				g.swap(args)
+			}
 		} else {
 			g.swap(args)
 		}
@@ -507,7 +664,7 @@ func (g *Goes) Main(args ...string) error {
 	if err == io.EOF {
 		err = nil
 	}
-	if err != nil && !k.IsDaemon() {
+	if _, quiet := err.(ExitStatus); err != nil && !k.IsDaemon() && !quiet {
 		name := args[0]
 		if len(name) == 0 {
 			if method, found := v.(akaer); found {
@@ -623,7 +780,12 @@ func (g *Goes) ensureTerminated(ls shellutils.List) (*shellutils.List, error) {
 				return &ls, nil
 			}
 		}
-		newls, err := shellutils.Parse(fmt.Sprintf("%s>>", term), g.Catline)
+		newls, err := g.Parse(fmt.Sprintf("%s>>", term))
+		if n := len(ls.Cmds); n > 0 && err == io.EOF {
+			cl := ls.Cmds[n-1]
+			err = cl.Term.Pos.Errorf("Unexpected EOF after `%s'",
+				term)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -640,31 +802,70 @@ type piperun struct {
 }
 
 func (g *Goes) ProcessList(ls shellutils.List) (*shellutils.List, *shellutils.Word, func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error, error) {
-	var (
-		pipeline []piperun
-		term     shellutils.Word
-	)
-
 	newls, err := g.ensureTerminated(ls)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 	ls = *newls
+	// copy the command lines that blocks may modify to reprocess them,
+	// with the lines read by blocks, for a job
+	cmds := append([]shellutils.Cmdline{}, ls.Cmds...)
+	line := g.Line
+	var lines []string
+	if catline := g.Catline; catline != nil {
+		defer func() { g.Catline = catline }()
+		g.Catline = func(prompt string) (string, error) {
+			s, err := catline(prompt)
+			if err == nil {
+				lines = append(lines, s)
+			}
+			return s, err
+		}
+	}
+	parent := g.procs
+	nextls, term, listfun, err := g.processList(ls)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if term.String() == "&" {
+		// those not left, unless the list was refilled by a block
+		text := cmds[:1]
+		if n := len(cmds) - len(nextls.Cmds); n > 0 && n <= len(cmds) {
+			text = cmds[:n]
+		}
+		listfun = g.makeJobFunc(shellutils.List{Cmds: cmds, Pos: ls.Pos},
+			lines, line, parent,
+			(&shellutils.List{Cmds: text}).Source())
+	}
+	return nextls, term, listfun, nil
+}
+
+// processList is ProcessList for the next list that returns, rather than
+// running as a job, one terminated by '&'.
+func (g *Goes) processList(ls shellutils.List) (*shellutils.List, *shellutils.Word, func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error, error) {
+	var (
+		pipeline []piperun
+		term     shellutils.Word
+	)
+
+	procs := &jobProcs{parent: g.procs}
+	g.procs = procs
+	defer func() { g.procs = procs.parent }()
 	for len(ls.Cmds) != 0 {
-		nextls, term, runner, err := g.ProcessPipeline(ls)
+		nextls, t, runner, err := g.ProcessPipeline(ls)
 		if err != nil {
 			return nil, nil, nil, err
 		}
 		ls = *nextls
-		pipeline = append(pipeline, piperun{f: runner, t: *term})
+		term = *t
+		pipeline = append(pipeline, piperun{f: runner, t: term})
 		if term.String() != "&&" && term.String() != "||" {
 			break
 		}
 	}
 
 	listfun, err := g.MakeListFunc(pipeline)
-
-	return &ls, &term, listfun, nil
+	return &ls, &term, listfun, err
 }
 
 func (g *Goes) MakeListFunc(pipeline []piperun) (func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error, error) {
@@ -672,13 +873,23 @@ func (g *Goes) MakeListFunc(pipeline []piperun) (func(stdin io.Reader, stdout io
 		var err error
 		skipNext := false
 		for _, runfun := range pipeline {
+			if g.Jumping() {
+				break
+			}
 			term := runfun.t
 			if !skipNext {
 				err = runfun.f(stdin, stdout, stderr)
 				if err != nil {
-					fmt.Fprintln(stderr, err)
+					if _, quiet := err.(ExitStatus); !quiet {
+						fmt.Fprintln(stderr, err)
+					}
 					g.Status = err
+					g.unset(err)
+				}
+				if t := term.String(); t != "&&" && t != "||" {
+					g.errexit()
 				}
+				g.runSignalTraps()
 				skipNext = false
 			}
 			if g.Status != nil {
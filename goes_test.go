@@ -0,0 +1,365 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/cmd/breakcmd"
+	"github.com/platinasystems/goes/cmd/casecmd"
+	"github.com/platinasystems/goes/cmd/cli"
+	"github.com/platinasystems/goes/cmd/declare"
+	"github.com/platinasystems/goes/cmd/docmd"
+	"github.com/platinasystems/goes/cmd/donecmd"
+	"github.com/platinasystems/goes/cmd/echo"
+	"github.com/platinasystems/goes/cmd/esaccmd"
+	"github.com/platinasystems/goes/cmd/exit"
+	"github.com/platinasystems/goes/cmd/export"
+	"github.com/platinasystems/goes/cmd/falsecmd"
+	"github.com/platinasystems/goes/cmd/fg"
+	"github.com/platinasystems/goes/cmd/ficmd"
+	"github.com/platinasystems/goes/cmd/forcmd"
+	"github.com/platinasystems/goes/cmd/function"
+	"github.com/platinasystems/goes/cmd/ifcmd"
+	"github.com/platinasystems/goes/cmd/kill"
+	"github.com/platinasystems/goes/cmd/read"
+	"github.com/platinasystems/goes/cmd/set"
+	"github.com/platinasystems/goes/cmd/sleep"
+	"github.com/platinasystems/goes/cmd/thencmd"
+	"github.com/platinasystems/goes/cmd/timeout"
+	"github.com/platinasystems/goes/cmd/trap"
+	"github.com/platinasystems/goes/cmd/truecmd"
+	"github.com/platinasystems/goes/cmd/wait"
+	"github.com/platinasystems/goes/cmd/whilecmd"
+)
+
+// goesTest is set in the environment of the test binary when it's run as
+// the shell by the tests, or as one of its forked commands.
+const goesTest = "GOES_TEST_MAIN"
+
+func TestMain(m *testing.M) {
+	if len(os.Getenv(goesTest)) > 0 {
+		g := &goes.Goes{
+			NAME: "goes",
+			ByName: map[string]cmd.Cmd{
+				"break":    &breakcmd.Command{},
+				"case":     casecmd.Command{},
+				"cli":      &cli.Command{},
+				"declare":  &declare.Command{},
+				"do":       docmd.Command{},
+				"done":     donecmd.Command{},
+				"echo":     echo.Command{},
+				"esac":     esaccmd.Command{},
+				"exit":     &exit.Command{},
+				"export":   &export.Command{},
+				"false":    falsecmd.Command{},
+				"fg":       &fg.Command{},
+				"fi":       ficmd.Command{},
+				"for":      forcmd.Command{},
+				"function": function.Command{},
+				"if":       ifcmd.Command{},
+				"kill":     &kill.Command{},
+				"read":     &read.Command{},
+				"set":      &set.Command{},
+				"sleep":    sleep.Command{},
+				"then":     thencmd.Command{},
+				"timeout":  &timeout.Command{},
+				"trap":     &trap.Command{},
+				"true":     truecmd.Command{},
+				"wait":     &wait.Command{},
+				"while":    whilecmd.Command{},
+			},
+		}
+		err := g.Main(os.Args...)
+		if _, quiet := err.(goes.ExitStatus); err != nil && !quiet {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(goes.ExitCode(err))
+	}
+	os.Exit(m.Run())
+}
+
+// run the test binary as goes with the given stdin and args; return its
+// combined output.
+func run(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	c := exec.CommandContext(ctx, os.Args[0])
+	c.Args = append([]string{"goes"}, args...)
+	c.Env = append(os.Environ(), goesTest+"=1")
+	c.Stdin = strings.NewReader(stdin)
+	out, err := c.CombinedOutput()
+	return string(out), err
+}
+
+func TestArgs(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"echo", "a", "-f", "b"}, "a -f b\n"},
+		{[]string{"echo", "a", "-x", "-no-liner", "b"}, "a -x -no-liner b\n"},
+		{[]string{"-"}, "0 \n"},
+		{[]string{"-", "x"}, "1 x\n"},
+		{[]string{"-", "x", "-y"}, "2 x\n"},
+	} {
+		got, err := run(t, "echo $# $1\n", tc.args...)
+		if err != nil {
+			t.Errorf("%q: %v: %s", tc.args, err, got)
+		} else if got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.args, got, tc.want)
+		}
+	}
+}
+
+// script runs the test binary as goes with the script on stdin and checks
+// its output.
+func script(t *testing.T, script, want string) {
+	t.Helper()
+	got, err := run(t, script, "-")
+	if strings.Contains(got, "DATA RACE") {
+		t.Errorf("%q: %s", script, got)
+	} else if err != nil {
+		t.Errorf("%q: %v: %s", script, err, got)
+	} else if got != want {
+		t.Errorf("%q: got %q, want %q", script, got, want)
+	}
+}
+
+func TestJobs(t *testing.T) {
+	for _, tc := range []struct{ script, want string }{
+		// run with -race
+		{"sleep 1 & X=1 true; wait\necho $X\n", "\n"},
+		{"X=a\nfor i in 1; do X=b; done & wait\necho $X\n", "a\n"},
+		{"for i in 1 2 3; do echo $i & wait; done\n", "1\n2\n3\n"},
+		{"function f { echo $1; X=b; }\nX=a\nf 1 & wait\necho $X\n",
+			"1\na\n"},
+		// the status of the job without an error of wait or fg
+		{"/bin/sleep 5 & kill %1; wait %1; echo $?\n", "143\n"},
+		{"sh -c 'exit 3' & wait $!; echo $?\n", "3\n"},
+		{"sh -c 'exit 4' &\nfg; echo $?\n", "sh -c 'exit 4'\n4\n"},
+	} {
+		script(t, tc.script, tc.want)
+	}
+}
+
+func TestJobRedirect(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "usage")
+	// run with -race
+	script(t, "function f { sleep 1; usage echo >"+fn+"; }\n"+
+		"f & sleep 2; echo fg; wait\n", "fg\n")
+	if b, err := ioutil.ReadFile(fn); err != nil {
+		t.Error(err)
+	} else if !strings.HasPrefix(string(b), "usage:\techo") {
+		t.Errorf("%s: %q", fn, b)
+	}
+}
+
+func TestExternal(t *testing.T) {
+	for _, tc := range []struct{ script, want string }{
+		// only exported variables
+		{"X=1; sh -c 'echo ${X:-unset}'\n", "unset\n"},
+		{"X=1 sh -c 'echo $X'; echo ${X:-unset}\n", "1\nunset\n"},
+		{"export X=2; sh -c 'echo $X'; echo $X\n", "2\n2\n"},
+		{"X=3; export X; sh -c 'echo $X'\n", "3\n"},
+		{"HOME=/h; sh -c 'echo $HOME'\n", "/h\n"},
+		// with the default action of the signals the shell ignores
+		{"sh -c 'kill -INT $$; echo ignored'; echo $?\n",
+			"130\n"},
+		{"trap '' INT; sh -c 'kill -INT $$; echo ignored'\n",
+			"ignored\n"},
+	} {
+		script(t, tc.script, tc.want)
+	}
+}
+
+func TestBlockRedirect(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "out")
+	for _, tc := range []struct{ script, want string }{
+		{"for i in 1 2; do echo $i; done >" + fn + "\n" +
+			"while read a; do echo [$a]; done <" + fn + "\n",
+			"[1]\n[2]\n"},
+		{"if true; then echo a; nosuch; fi >" + fn + " 2>&1\n" +
+			"cat " + fn + "\n", "a\nnosuch: command not found\n"},
+		{"case a in a) echo b;; esac >>" + fn + "; cat " + fn + "\n",
+			"a\nnosuch: command not found\nb\n"},
+		{"seq 2 | while read a; do echo $a; done | cat\n", "1\n2\n"},
+		{"while read a; do echo $a; done <<EOF\nx\nEOF\necho y\n",
+			"x\ny\n"},
+		// the error of a command is that of its redirected stderr
+		{"nosuch 2>/dev/null; echo $?\n", "127\n"},
+		{"nosuch 2>&1 >/dev/null | cat\n",
+			"nosuch: command not found\n"},
+		{"nosuch 2>/dev/null | true; echo $PIPESTATUS\n", "127 0\n"},
+	} {
+		script(t, tc.script, tc.want)
+	}
+	got, _ := run(t, "for i in 1; do echo $i; done >"+fn+" x\n", "-")
+	if !strings.Contains(got, "unexpected text after done") {
+		t.Errorf("text after done: %q", got)
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	for _, tc := range []struct{ script, want string }{
+		// the writer sees the reader quit
+		{"seq 100000 | head -1\n", "1\n"},
+		{"seq 3 | cat | cat\n", "1\n2\n3\n"},
+		{"true | false; echo $PIPESTATUS\n", "0 1\n"},
+		{"sh -c 'exit 3' | sh -c 'exit 4'; echo $PIPESTATUS\n",
+			"sh: exit status 3\n3 4\n"},
+		{"false; echo ${PIPESTATUS[0]}\n", "1\n"},
+		{"echo a | false | true; echo ${PIPESTATUS[1]} $?\n",
+			"false: exit status 1\n1 0\n"},
+	} {
+		script(t, tc.script, tc.want)
+	}
+}
+
+func TestCmdsub(t *testing.T) {
+	for _, tc := range []struct{ script, want string }{
+		// in a subshell
+		{"trap 'echo bye' EXIT\nx=$(exit 3); echo $? ${x:-empty}\n",
+			"3 empty\nbye\n"},
+		{"x=$(y=2; echo a); echo $x ${y:-unset}\n", "a unset\n"},
+		{"x=$(false); echo $?; x=$(true); echo $?\n", "1\n0\n"},
+		{"x=$(set -u; echo $nope; echo b); echo $x\n",
+			"nope: unbound variable\n\n"},
+		{"function f { echo in f; X=b; }\nX=a; echo $(f) $X\n",
+			"in f a\n"},
+		{"echo $(case a in a) echo y;; esac) \"$(echo ')')\"\n",
+			"y )\n"},
+		{"echo $(echo a & wait) $(declare -f)\n", "a\n"},
+	} {
+		script(t, tc.script, tc.want)
+	}
+	// errexit with the status of the substitution
+	got, err := run(t, "set -e; x=$(false); echo unreachable\n", "-")
+	if goes.ExitCode(err) != 1 || len(got) > 0 {
+		t.Errorf("errexit: %v: %q", err, got)
+	}
+}
+
+func TestCase(t *testing.T) {
+	for _, tc := range []struct{ script, want string }{
+		{"case b in a|b) echo ab;; *) echo other;; esac\n", "ab\n"},
+		{"case c in (a) echo a;; (*) echo other;; esac\n", "other\n"},
+		// a quoted parenthesis is a pattern
+		{"case \")\" in \")\") echo y;; esac\n", "y\n"},
+		{"case \"(\" in x|'(') echo y;; esac\n", "y\n"},
+		{"case a in \\)) echo n;; *) echo y;; esac\n", "y\n"},
+	} {
+		script(t, tc.script, tc.want)
+	}
+}
+
+func TestFunction(t *testing.T) {
+	for _, tc := range []struct{ script, want string }{
+		{"function f { echo a; }; echo b\nf\n", "b\na\n"},
+		{"function f {\n\techo a\n}\nf\n", "a\n"},
+		{"function f { if true; then\necho a; fi; }; echo b\nf\n",
+			"b\na\n"},
+	} {
+		script(t, tc.script, tc.want)
+		// declare -f must print a definition that redefines it
+		def, err := run(t, tc.script+"declare -f\n", "-")
+		if err != nil {
+			t.Errorf("%q: %v: %s", tc.script, err, def)
+			continue
+		}
+		def = strings.TrimPrefix(def, tc.want)
+		if !strings.HasPrefix(def, "function f {\n") {
+			t.Errorf("%q: declare -f: %q", tc.script, def)
+			continue
+		}
+		script(t, def+"f\ndeclare -f\n", strings.TrimPrefix(tc.want,
+			"b\n")+def)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	for _, tc := range []struct{ script, want string }{
+		{"timeout 5 sh -c 'exit 3'; echo $?\n", "3\n"},
+		// with the default action of the signal
+		{"timeout -s INT -k 1 1 /bin/sleep 5; echo $?\n", "124\n"},
+		{"trap '' INT; timeout -s INT -k 1 1 /bin/sleep 5; echo $?\n",
+			"124\n"},
+		// sent to the group
+		{"timeout 1 sh -c '(/bin/sleep 2; echo leaked) & wait'\n" +
+			"echo $?; sleep 2\n", "124\n"},
+	} {
+		script(t, tc.script, tc.want)
+	}
+}
+
+func TestSyntax(t *testing.T) {
+	dir := t.TempDir()
+	for i, tc := range []struct{ script, want string }{
+		{"echo a\n", ""},
+		{"if true; then\n", ":1:1: Unexpected EOF while looking for " +
+			"matching `fi'\n"},
+		{"if false; then :\nelse\n", ":1:1: Unexpected EOF while " +
+			"looking for matching `fi'\n"},
+		{"function f {\n", ":1:1: Unexpected EOF while looking for " +
+			"matching `}'\n"},
+		{"while true; do if true; then\n", ":1:16: Unexpected EOF " +
+			"while looking for matching `fi'\n"},
+	} {
+		// without "#!/usr/bin/goes"
+		fn := filepath.Join(dir, fmt.Sprint(i))
+		if err := ioutil.WriteFile(fn, []byte(tc.script),
+			0644); err != nil {
+			t.Fatal(err)
+		}
+		want := tc.want
+		if len(want) > 0 {
+			want = fn + want
+		}
+		got, err := run(t, "", "-n", fn)
+		if (err != nil) != (len(want) > 0) || got != want {
+			t.Errorf("%q: %v: got %q, want %q", tc.script, err, got,
+				want)
+		}
+	}
+}
+
+func TestNounset(t *testing.T) {
+	for _, tc := range []struct {
+		script, want string
+		fails        bool
+	}{
+		{"set -u; echo $nope; echo unreachable\n",
+			"nope: unbound variable\n", true},
+		{"set -u\nfor i in a $nope; do echo $i; done\necho unreachable\n",
+			"nope: unbound variable\n", true},
+		{"set -u; echo ${nope:-unset} $#\n", "unset 0\n", false},
+		// without set -u
+		{"echo ${nope:?is required}; echo unreachable\n",
+			"nope: is required\n", true},
+		{"x=; for i in ${x:?}; do :; done; echo unreachable\n",
+			"x: parameter null or not set\n", true},
+		{"echo ${x:-${nope:?}}\n", "nope: parameter null or not set\n",
+			true},
+	} {
+		got, err := run(t, tc.script, "-")
+		if (err != nil) != tc.fails {
+			t.Errorf("%q: exit %v", tc.script, err)
+		}
+		if got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.script, got, tc.want)
+		}
+	}
+}
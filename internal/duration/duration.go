@@ -0,0 +1,47 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package duration parses the DURATION argument of commands like timeout and
+// hwait.
+package duration
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parse returns the duration of s, a number of seconds, which may have a
+// fraction and a unit suffix of s, m, h, or d, e.g. "2.5" or "1m"; or else
+// that of time.ParseDuration, e.g. "1m30s" or "250ms".
+func Parse(s string) (time.Duration, error) {
+	unit := time.Second
+	num := s
+	if n := len(s); n > 0 {
+		switch s[n-1] {
+		case 's':
+			num = s[:n-1]
+		case 'm':
+			unit, num = time.Minute, s[:n-1]
+		case 'h':
+			unit, num = time.Hour, s[:n-1]
+		case 'd':
+			unit, num = 24*time.Hour, s[:n-1]
+		}
+	}
+	if f, err := strconv.ParseFloat(num, 64); err == nil &&
+		!strings.ContainsAny(num, "xXpP") {
+		if f < 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+			return 0, fmt.Errorf("%s: invalid duration", s)
+		}
+		return time.Duration(f * float64(unit)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s: invalid duration", s)
+	}
+	return d, nil
+}
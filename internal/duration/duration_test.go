@@ -0,0 +1,39 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package duration
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want time.Duration
+	}{
+		{"3", 3 * time.Second},
+		{"2.5", 2500 * time.Millisecond},
+		{"10s", 10 * time.Second},
+		{"1.5m", 90 * time.Second},
+		{"2h", 2 * time.Hour},
+		{"1d", 24 * time.Hour},
+		{"1m30s", 90 * time.Second},
+		{"250ms", 250 * time.Millisecond},
+		{"0", 0},
+	} {
+		got, err := Parse(tc.s)
+		if err != nil {
+			t.Errorf("%s: %v", tc.s, err)
+		} else if got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.s, got, tc.want)
+		}
+	}
+	for _, s := range []string{"", "-1", "s", "1x", "0x10", "inf"} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("%s: no error", s)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package histfile maintains a bounded, de-duplicated command line history
+// in a per user file that may be shared by concurrent sessions, e.g. those
+// of the console, sshd, and telnetd.
+package histfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// DefaultSize is the number of lines kept without $HISTSIZE.
+const DefaultSize = 1000
+
+// Name returns $HISTFILE, or else .goes_history in the user's home directory.
+func Name(getenv func(string) string) string {
+	if s := getenv("HISTFILE"); len(s) > 0 {
+		return s
+	}
+	home := getenv("HOME")
+	if len(home) == 0 {
+		u, err := user.Current()
+		if err != nil {
+			return ""
+		}
+		home = u.HomeDir
+	}
+	return filepath.Join(home, ".goes_history")
+}
+
+// Size returns $HISTSIZE, or else DefaultSize.
+func Size(getenv func(string) string) int {
+	if n, err := strconv.Atoi(getenv("HISTSIZE")); err == nil && n > 0 {
+		return n
+	}
+	return DefaultSize
+}
+
+// History is the command line history of a session, oldest first. Without a
+// file Name, it's only kept in memory.
+type History struct {
+	Name  string
+	Size  int
+	lines []string
+}
+
+func New(name string, size int) *History {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	return &History{Name: name, Size: size}
+}
+
+// Lines returns the history, oldest first; line N is Lines()[N-1].
+func (h *History) Lines() []string {
+	return h.lines
+}
+
+// Load replaces the history with that of the file, which may include the
+// lines added by other sessions. Without a file, the history is unchanged.
+func (h *History) Load() error {
+	if len(h.Name) == 0 {
+		return nil
+	}
+	f, err := os.Open(h.Name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return err
+	}
+	defer f.Close()
+	if err = syscall.Flock(int(f.Fd()), syscall.LOCK_SH); err != nil {
+		return err
+	}
+	lines, err := read(f)
+	if err != nil {
+		return err
+	}
+	h.lines = compact(lines, h.Size)
+	return nil
+}
+
+// Add appends the line to the history and its file unless it's blank or
+// the same as the previous line. The file is rewritten without duplicates
+// once it has twice Size lines.
+func (h *History) Add(line string) error {
+	if len(strings.TrimSpace(line)) == 0 {
+		return nil
+	}
+	if n := len(h.lines); n > 0 && h.lines[n-1] == line {
+		return nil
+	}
+	h.lines = append(h.lines, line)
+	if n := len(h.lines); n > h.Size {
+		h.lines = h.lines[n-h.Size:]
+	}
+	if len(h.Name) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(h.Name, os.O_RDWR|os.O_CREATE|os.O_APPEND,
+		0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return err
+	}
+	lines, err := read(f)
+	if err != nil {
+		return err
+	}
+	if n := len(lines); n > 0 && lines[n-1] == line {
+		return nil
+	}
+	if len(lines)+1 < 2*h.Size {
+		_, err = fmt.Fprintln(f, line)
+		return err
+	}
+	return rewrite(f, compact(append(lines, line), h.Size))
+}
+
+// Clear empties the history and its file.
+func (h *History) Clear() error {
+	h.lines = nil
+	if len(h.Name) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(h.Name, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return err
+	}
+	return f.Truncate(0)
+}
+
+// Expand replaces these history references in the line, other than those
+// escaped or single quoted.
+//
+//	!!	the previous line
+//	!N	line N of the history
+//	!-N	the Nth previous line
+//	!PREFIX	the most recent line beginning with PREFIX
+//
+// A '!' that follows '$' or '[', e.g. $! or [!a-z], or that is followed by
+// a blank, '=', '(', or the end of line isn't a reference.
+func (h *History) Expand(line string) (string, error) {
+	if !strings.ContainsRune(line, '!') {
+		return line, nil
+	}
+	var (
+		b      strings.Builder
+		squote bool
+		dquote bool
+	)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && !squote && i+1 < len(line):
+			b.WriteByte(c)
+			i++
+			c = line[i]
+		case c == '\'' && !dquote:
+			squote = !squote
+		case c == '"' && !squote:
+			dquote = !dquote
+		case c == '!' && !squote && i+1 < len(line) &&
+			!strings.ContainsRune(" \t=(", rune(line[i+1])) &&
+			(i == 0 || !strings.ContainsRune("$[", rune(line[i-1]))):
+			event := reference(line[i+1:])
+			s, err := h.event(event)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+			i += len(event)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), nil
+}
+
+// reference returns the event that follows '!'.
+func reference(s string) string {
+	if s[0] == '!' {
+		return s[:1]
+	}
+	i := 0
+	if s[0] == '-' {
+		i++
+	}
+	if i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		return s[:i]
+	}
+	if i := strings.IndexAny(s, " \t;&|<>()\"'"); i > 0 {
+		return s[:i]
+	}
+	return s
+}
+
+func (h *History) event(event string) (string, error) {
+	n := len(h.lines)
+	s := event
+	if s == "!" {
+		s = "-1"
+	}
+	if i, err := strconv.Atoi(s); err == nil {
+		if i < 0 {
+			i += n + 1
+		}
+		if i > 0 && i <= n {
+			return h.lines[i-1], nil
+		}
+	} else {
+		for i := n - 1; i >= 0; i-- {
+			if strings.HasPrefix(h.lines[i], event) {
+				return h.lines[i], nil
+			}
+		}
+	}
+	return "", fmt.Errorf("!%s: event not found", event)
+}
+
+func read(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+func rewrite(f *os.File, lines []string) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+	return w.Flush()
+}
+
+// compact returns the last size lines after removing the earlier duplicates.
+func compact(lines []string, size int) []string {
+	last := make(map[string]int, len(lines))
+	for i, line := range lines {
+		last[line] = i
+	}
+	var kept []string
+	for i, line := range lines {
+		if last[line] == i {
+			kept = append(kept, line)
+		}
+	}
+	if n := len(kept); n > size {
+		kept = kept[n-size:]
+	}
+	return kept
+}
@@ -0,0 +1,86 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package histfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShared(t *testing.T) {
+	dir, err := ioutil.TempDir("", "histfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "history")
+	a, b := New(name, 3), New(name, 3)
+	for _, line := range []string{"ls", "ls", " ", "date"} {
+		if err := a.Add(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(a.Lines(), ","), "ls,date"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	for _, line := range []string{"uptime", "ls", "df", "who"} {
+		if err := b.Add(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(a.Lines(), ","), "ls,df,who"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	buf, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rewritten without duplicates at twice the size
+	if got, want := string(buf), "ls\ndf\nwho\n"; got != want {
+		t.Errorf("file %q, want %q", got, want)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	h := New("", 10)
+	for _, line := range []string{"echo one", "ls -l", "echo two"} {
+		h.Add(line)
+	}
+	for _, tc := range []struct{ line, want string }{
+		{"!!", "echo two"},
+		{"!1 | wc", "echo one | wc"},
+		{"!-2", "ls -l"},
+		{"!ec;date", "echo two;date"},
+		{"sudo !l", "sudo ls -l"},
+		{`echo '!!' \!! "!!"`, `echo '!!' \!! "echo two"`},
+		{"[ ! -e x ] && [ a != b ]", "[ ! -e x ] && [ a != b ]"},
+		{"echo $!;", "echo $!;"},
+		{"ls [!a-z]*", "ls [!a-z]*"},
+		{"echo hi!", "echo hi!"},
+		{"echo ! x", "echo ! x"},
+		{"echo !\tx", "echo !\tx"},
+		{"x!=y", "x!=y"},
+		{"echo !(a|b)", "echo !(a|b)"},
+		{"!x", "!x: event not found"},
+		{"!9", "!9: event not found"},
+	} {
+		got, err := h.Expand(tc.line)
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.line, got, tc.want)
+		}
+	}
+}
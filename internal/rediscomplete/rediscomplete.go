@@ -0,0 +1,86 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package rediscomplete completes the KEY and FIELD arguments of the redis
+// commands, e.g. hget, with the names of those in redisd.
+package rediscomplete
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/platinasystems/redis"
+)
+
+// Keys returns the redis keys that begin with the KEY argument, which is
+// the first other than leading flags, e.g. "-q".
+func Keys(args ...string) []string {
+	args = skipFlags(args)
+	switch len(args) {
+	case 0:
+		return keys("")
+	case 1:
+		return keys(args[0])
+	}
+	return nil
+}
+
+// KeyField returns the redis keys that begin with the KEY argument or, with
+// a second argument, the fields of KEY that begin with that, e.g. "hget
+// platina fan_tray." completes to the fan tray fields of the default hash.
+func KeyField(args ...string) []string {
+	args = skipFlags(args)
+	switch len(args) {
+	case 0, 1:
+		return Keys(args...)
+	case 2:
+		return fields(args[0], args[1])
+	}
+	return nil
+}
+
+func skipFlags(args []string) []string {
+	for len(args) > 1 && strings.HasPrefix(args[0], "-") {
+		args = args[1:]
+	}
+	return args
+}
+
+func keys(prefix string) []string {
+	list, err := redis.Keys(escape(prefix) + "*")
+	if err != nil {
+		return nil
+	}
+	sort.Strings(list)
+	return list
+}
+
+func fields(key, prefix string) (list []string) {
+	all, err := redis.Hkeys(key)
+	if err != nil {
+		return nil
+	}
+	for _, field := range all {
+		if strings.HasPrefix(field, prefix) {
+			list = append(list, field)
+		}
+	}
+	sort.Strings(list)
+	return
+}
+
+// escape the glob pattern characters of a KEYS prefix
+func escape(s string) string {
+	if !strings.ContainsAny(s, `*?[]\`) {
+		return s
+	}
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`*?[]\`, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,501 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package shellutils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var errDivideByZero = errors.New("division by 0")
+
+const (
+	arithEnd = iota
+	arithNum
+	arithName
+	arithOp
+)
+
+// arithOps are the operators of arithmetic expressions, longest first.
+var arithOps = []string{
+	"<<=", ">>=",
+	"**", "++", "--", "<<", ">>", "<=", ">=", "==", "!=", "&&", "||",
+	"*=", "/=", "%=", "+=", "-=", "&=", "^=", "|=",
+	"+", "-", "*", "/", "%", "<", ">", "&", "|", "^", "!", "~",
+	"?", ":", "=", "(", ")", ",",
+}
+
+// arithLevels are the left associative binary operators by increasing
+// precedence.
+var arithLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"|"},
+	{"^"},
+	{"&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+type arith struct {
+	expr string
+	s    string
+	e    Expander
+	kind int
+	tok  string
+	// noeval is non-zero while parsing the operand of a short circuit
+	// operator that isn't evaluated
+	noeval int
+}
+
+// Arith evaluates the C-like integer expression. Variables are referenced
+// by name, without '$', and have the value 0 if unset or empty. Assignments
+// (e.g. i=i+1, i++, n+=2) require that the Expander is also a Setenver.
+// Numbers may be decimal, octal with a leading 0, or hex with a leading 0x.
+func Arith(expr string, e Expander) (int64, error) {
+	a := &arith{expr: expr, s: expr, e: e}
+	if err := a.next(); err != nil {
+		return 0, err
+	}
+	if a.kind == arithEnd {
+		return 0, nil
+	}
+	v, err := a.comma()
+	if err != nil {
+		return 0, err
+	}
+	if a.kind != arithEnd {
+		return 0, a.unexpected()
+	}
+	return v, nil
+}
+
+func (a *arith) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s: %s", strings.TrimSpace(a.expr),
+		fmt.Sprintf(format, args...))
+}
+
+func (a *arith) unexpected() error {
+	if a.kind == arithEnd {
+		return a.errorf("syntax error: operand expected")
+	}
+	return a.errorf("syntax error: `%s' unexpected", a.tok)
+}
+
+// next scans the next token.
+func (a *arith) next() error {
+	a.s = strings.TrimLeftFunc(a.s, unicode.IsSpace)
+	if len(a.s) == 0 {
+		a.kind, a.tok = arithEnd, ""
+		return nil
+	}
+	r, _ := utf8.DecodeRuneInString(a.s)
+	i := 0
+	switch {
+	case unicode.IsDigit(r):
+		a.kind = arithNum
+		for i < len(a.s) && (a.s[i] == '_' || a.s[i] < utf8.RuneSelf &&
+			(unicode.IsLetter(rune(a.s[i])) ||
+				unicode.IsDigit(rune(a.s[i])))) {
+			i++
+		}
+	case r == '_' || unicode.IsLetter(r):
+		a.kind = arithName
+		for i < len(a.s) {
+			r, wid := utf8.DecodeRuneInString(a.s[i:])
+			if r != '_' && !unicode.IsLetter(r) &&
+				!unicode.IsDigit(r) {
+				break
+			}
+			i += wid
+		}
+	default:
+		a.kind = arithOp
+		for _, op := range arithOps {
+			if strings.HasPrefix(a.s, op) {
+				i = len(op)
+				break
+			}
+		}
+		if i == 0 {
+			return a.errorf("syntax error: invalid character `%c'",
+				r)
+		}
+	}
+	a.tok, a.s = a.s[:i], a.s[i:]
+	return nil
+}
+
+func (a *arith) is(ops ...string) bool {
+	if a.kind != arithOp {
+		return false
+	}
+	for _, op := range ops {
+		if a.tok == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *arith) comma() (int64, error) {
+	v, err := a.assign()
+	for err == nil && a.is(",") {
+		if err = a.next(); err == nil {
+			v, err = a.assign()
+		}
+	}
+	return v, err
+}
+
+func (a *arith) assign() (int64, error) {
+	if a.kind == arithName {
+		s, name := a.s, a.tok
+		if err := a.next(); err != nil {
+			return 0, err
+		}
+		if a.kind == arithOp && strings.HasSuffix(a.tok, "=") &&
+			!a.is("==", "!=", "<=", ">=") {
+			op := strings.TrimSuffix(a.tok, "=")
+			if err := a.next(); err != nil {
+				return 0, err
+			}
+			v, err := a.assign()
+			if err != nil {
+				return 0, err
+			}
+			if len(op) > 0 {
+				old, err := a.value(name)
+				if err != nil {
+					return 0, err
+				}
+				if v, err = a.binary(op, old, v); err != nil {
+					return 0, err
+				}
+			}
+			return v, a.setenv(name, v)
+		}
+		// not an assignment, so rescan from the name
+		a.s, a.kind, a.tok = s, arithName, name
+	}
+	return a.ternary()
+}
+
+func (a *arith) ternary() (int64, error) {
+	cond, err := a.level(0)
+	if err != nil || !a.is("?") {
+		return cond, err
+	}
+	if err = a.next(); err != nil {
+		return 0, err
+	}
+	if cond == 0 {
+		a.noeval++
+	}
+	v1, err := a.comma()
+	if cond == 0 {
+		a.noeval--
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !a.is(":") {
+		return 0, a.errorf("syntax error: `:' expected")
+	}
+	if err = a.next(); err != nil {
+		return 0, err
+	}
+	if cond != 0 {
+		a.noeval++
+	}
+	v2, err := a.ternary()
+	if cond != 0 {
+		a.noeval--
+		return v1, err
+	}
+	return v2, err
+}
+
+// level parses the binary operators of the given precedence and higher.
+func (a *arith) level(n int) (int64, error) {
+	if n == len(arithLevels) {
+		return a.power()
+	}
+	v, err := a.level(n + 1)
+	for err == nil && a.is(arithLevels[n]...) {
+		op := a.tok
+		if err = a.next(); err != nil {
+			break
+		}
+		skip := (op == "&&" && v == 0) || (op == "||" && v != 0)
+		if skip {
+			a.noeval++
+		}
+		var rhs int64
+		rhs, err = a.level(n + 1)
+		if skip {
+			a.noeval--
+		}
+		if err != nil {
+			break
+		}
+		v, err = a.binary(op, v, rhs)
+	}
+	return v, err
+}
+
+func (a *arith) power() (int64, error) {
+	v, err := a.unary()
+	if err != nil || !a.is("**") {
+		return v, err
+	}
+	if err = a.next(); err != nil {
+		return 0, err
+	}
+	exp, err := a.power()
+	if err != nil {
+		return 0, err
+	}
+	return a.binary("**", v, exp)
+}
+
+func (a *arith) unary() (int64, error) {
+	if !a.is("+", "-", "!", "~", "++", "--") {
+		return a.postfix()
+	}
+	op := a.tok
+	if err := a.next(); err != nil {
+		return 0, err
+	}
+	if op == "++" || op == "--" {
+		if a.kind != arithName {
+			return 0, a.errorf("syntax error: `%s' requires a variable",
+				op)
+		}
+		name := a.tok
+		if err := a.next(); err != nil {
+			return 0, err
+		}
+		v, err := a.value(name)
+		if err != nil {
+			return 0, err
+		}
+		if op == "++" {
+			v++
+		} else {
+			v--
+		}
+		return v, a.setenv(name, v)
+	}
+	v, err := a.unary()
+	if err != nil {
+		return 0, err
+	}
+	switch op {
+	case "-":
+		v = -v
+	case "!":
+		if v == 0 {
+			v = 1
+		} else {
+			v = 0
+		}
+	case "~":
+		v = ^v
+	}
+	return v, nil
+}
+
+func (a *arith) postfix() (int64, error) {
+	switch a.kind {
+	case arithNum:
+		v, err := strconv.ParseInt(a.tok, 0, 64)
+		if err != nil {
+			return 0, a.errorf("%s: invalid number", a.tok)
+		}
+		return v, a.next()
+	case arithName:
+		name := a.tok
+		if err := a.next(); err != nil {
+			return 0, err
+		}
+		v, err := a.value(name)
+		if err != nil || !a.is("++", "--") {
+			return v, err
+		}
+		nv := v + 1
+		if a.tok == "--" {
+			nv = v - 1
+		}
+		if err = a.next(); err != nil {
+			return 0, err
+		}
+		return v, a.setenv(name, nv)
+	}
+	if !a.is("(") {
+		return 0, a.unexpected()
+	}
+	if err := a.next(); err != nil {
+		return 0, err
+	}
+	v, err := a.comma()
+	if err != nil {
+		return 0, err
+	}
+	if !a.is(")") {
+		return 0, a.errorf("syntax error: `)' expected")
+	}
+	return v, a.next()
+}
+
+// value returns the integer value of the named variable.
+func (a *arith) value(name string) (int64, error) {
+	if a.noeval > 0 {
+		return 0, nil
+	}
+	s, err := getenv(a.e, name)
+	if err != nil {
+		return 0, err
+	}
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return 0, nil
+	}
+	v, err := strconv.ParseInt(s, 0, 64)
+	if err != nil {
+		return 0, a.errorf("%s: %s: invalid number", name, s)
+	}
+	return v, nil
+}
+
+func (a *arith) setenv(name string, v int64) error {
+	if a.noeval > 0 {
+		return nil
+	}
+	m, ok := a.e.(Setenver)
+	if !ok {
+		return a.errorf("%s: cannot assign", name)
+	}
+	m.Setenv(name, strconv.FormatInt(v, 10))
+	return nil
+}
+
+func (a *arith) binary(op string, x, y int64) (int64, error) {
+	b := func(t bool) int64 {
+		if t {
+			return 1
+		}
+		return 0
+	}
+	switch op {
+	case "||":
+		return b(x != 0 || y != 0), nil
+	case "&&":
+		return b(x != 0 && y != 0), nil
+	case "|":
+		return x | y, nil
+	case "^":
+		return x ^ y, nil
+	case "&":
+		return x & y, nil
+	case "==":
+		return b(x == y), nil
+	case "!=":
+		return b(x != y), nil
+	case "<":
+		return b(x < y), nil
+	case "<=":
+		return b(x <= y), nil
+	case ">":
+		return b(x > y), nil
+	case ">=":
+		return b(x >= y), nil
+	case "<<":
+		return x << (uint64(y) & 63), nil
+	case ">>":
+		return x >> (uint64(y) & 63), nil
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/", "%":
+		if y == 0 {
+			if a.noeval > 0 {
+				return 0, nil
+			}
+			return 0, a.errorf("%s", errDivideByZero)
+		}
+		if op == "/" {
+			return x / y, nil
+		}
+		return x % y, nil
+	case "**":
+		if y < 0 {
+			return 0, a.errorf("exponent less than 0")
+		}
+		v := int64(1)
+		for ; y > 0; y-- {
+			v *= x
+		}
+		return v, nil
+	}
+	return 0, a.errorf("%s: unknown operator", op)
+}
+
+// parseArith adds the arithmetic expansion that follows "$((" to the Word
+// and returns the remaining input. The expression may include variable
+// references and command substitutions that are expanded before it's
+// evaluated. It returns false if the "$((" doesn't have a matching "))" on
+// the line, so it may instead be a command substitution of a subshell.
+func (w *Word) parseArith(s string, quoted bool) (string, bool, error) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+				continue
+			}
+			if i+1 == len(s) || s[i+1] != ')' {
+				return s, false, nil
+			}
+			expr, _, err := parseOperand(s[:i], nil, true, "")
+			if err != nil {
+				return "", true, err
+			}
+			w.addToken(Token{
+				T:    TokenArith,
+				Q:    quoted,
+				Args: []Word{expr},
+			})
+			return s[i+2:], true, nil
+		}
+	}
+	return s, false, nil
+}
+
+// expandArith returns the value of the arithmetic expansion Token.
+func (t *Token) expandArith(e Expander) (string, error) {
+	expr, err := t.Args[0].Expand(e)
+	if err != nil {
+		return "", err
+	}
+	v, err := Arith(expr, e)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(v, 10), nil
+}
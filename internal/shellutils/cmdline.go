@@ -4,21 +4,20 @@
 
 package shellutils
 
-import (
-	"fmt"
-)
-
 // Cmdline is a slice of Words which may be variable setting, a command,
 // or arguments to that command. There is a seperate terminator which
-// is either a pipeline operator (|) or a list operator (; & || &&).
+// is either a pipeline operator (|), a list operator (; & || &&), or the
+// case item terminator (;;). Pos is that of the first Word.
 type Cmdline struct {
 	Cmds []Word
 	Term Word
+	Pos  Pos
 }
 
 func (c *Cmdline) add(w *Word) {
 	if c.Cmds == nil {
 		c.Cmds = make([]Word, 0)
+		c.Pos = w.Pos
 	}
 	c.Cmds = append(c.Cmds, *w)
 	*w = Word{}
@@ -27,35 +26,27 @@ func (c *Cmdline) add(w *Word) {
 // Slice takes a parsed command line and returns a
 // map of the environment variables declared in the command,
 // and a slice of the command and its arguments as strings
-func (c *Cmdline) Slice(getenv func(string) string) (map[string]string, []string) {
+func (c *Cmdline) Slice(e Expander) (map[string]string, []string, error) {
 	envmap := make(map[string]string)
 	Cmdline := make([]string, 0)
 
 	for _, w := range c.Cmds {
-		s := ""
-		isEnvset := false
-		envsetOffset := 0
-		for _, t := range w.Tokens {
-			switch t.T {
-			case TokenLiteral:
-				s += t.V
-			case TokenEnvget:
-				s += getenv(t.V)
-			case TokenEnvset:
-				if !isEnvset {
-					isEnvset = true
-					envsetOffset = len(s)
+		if len(Cmdline) == 0 {
+			if name, tokens := w.assignment(); len(name) > 0 {
+				value := Word{Tokens: tokens}
+				s, err := value.Expand(e)
+				if err != nil {
+					return nil, nil, err
 				}
-				s += t.V
-			default:
-				panic(fmt.Errorf("Unknown Token %v", t))
+				envmap[name] = s
+				continue
 			}
 		}
-		if len(Cmdline) == 0 && isEnvset && envsetOffset != 0 {
-			envmap[s[0:envsetOffset]] = s[envsetOffset+1:]
-		} else {
-			Cmdline = append(Cmdline, s)
+		fields, err := w.Fields(e)
+		if err != nil {
+			return nil, nil, err
 		}
+		Cmdline = append(Cmdline, fields...)
 	}
-	return envmap, Cmdline
+	return envmap, Cmdline, nil
 }
@@ -0,0 +1,171 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package shellutils
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	errMissingEndParen     = errors.New("Unexpected EOF while looking for matching `)'")
+	errMissingEndBackquote = errors.New("Unexpected EOF while looking for matching ``'")
+)
+
+// parseCmdsub adds the command substitution that follows "$(" to the Word
+// and returns the remaining input. The command list may span lines read
+// from srcin, and may include nested substitutions, quoted parentheses, and
+// the unmatched ')' of case patterns.
+func (w *Word) parseCmdsub(s string, srcin func(string) (string, error), quoted bool) (string, error) {
+	var (
+		b     strings.Builder
+		err   error
+		quote rune
+		// word is the unquoted text of the current word, if any
+		word strings.Builder
+		// cases has the depth of each open case
+		cases []int
+	)
+	depth := 1
+	// command is true where a word may be a command or reserved word
+	command := true
+	endWord := func() {
+		switch v := word.String(); {
+		case len(v) == 0:
+			return
+		case v == "case" && command:
+			cases = append(cases, depth)
+			command = false
+		case v == "esac" && len(cases) > 0 &&
+			cases[len(cases)-1] == depth:
+			cases = cases[:len(cases)-1]
+			command = false
+		default:
+			command = reservedCommand[v] && command
+		}
+		word.Reset()
+	}
+	for {
+		for len(s) > 0 {
+			r, wid := utf8.DecodeRuneInString(s)
+			s = s[wid:]
+			switch {
+			case quote == '\'':
+				if r == '\'' {
+					quote = 0
+				}
+			case r == '\\':
+				word.WriteRune(r)
+				if len(s) > 0 {
+					b.WriteRune(r)
+					r, wid = utf8.DecodeRuneInString(s)
+					s = s[wid:]
+				}
+			case quote == '"':
+				if r == '"' {
+					quote = 0
+				}
+			case r == '\'' || r == '"':
+				word.WriteRune(r)
+				quote = r
+			case r == ' ' || r == '\t':
+				endWord()
+			case r == ';' || r == '&' || r == '|':
+				endWord()
+				command = true
+			case r == '(':
+				endWord()
+				depth++
+				command = true
+			case r == ')':
+				endWord()
+				if len(cases) > 0 && cases[len(cases)-1] == depth {
+					// the end of a case pattern
+					command = true
+					break
+				}
+				depth--
+				if depth == 0 {
+					w.addToken(Token{
+						V: b.String(),
+						T: TokenCmdsub,
+						Q: quoted,
+					})
+					return s, nil
+				}
+				command = false
+			default:
+				word.WriteRune(r)
+			}
+			b.WriteRune(r)
+		}
+		b.WriteRune('\n')
+		if quote == 0 {
+			endWord()
+			command = true
+		}
+		s, err = srcin("> ")
+		if err != nil {
+			if err == io.EOF {
+				return "", errMissingEndParen
+			}
+			return "", err
+		}
+	}
+}
+
+// reservedCommand are the reserved words that may be followed by a command.
+var reservedCommand = map[string]bool{
+	"!":     true,
+	"do":    true,
+	"elif":  true,
+	"else":  true,
+	"if":    true,
+	"then":  true,
+	"until": true,
+	"while": true,
+	"{":     true,
+	"}":     true,
+}
+
+// parseBackquote adds the command substitution that follows '`' to the Word
+// and returns the remaining input. Within backquotes, a backslash retains its
+// literal meaning except when followed by '$', '`', or '\'.
+func (w *Word) parseBackquote(s string, srcin func(string) (string, error), quoted bool) (string, error) {
+	var (
+		b   strings.Builder
+		err error
+	)
+	for {
+		for len(s) > 0 {
+			r, wid := utf8.DecodeRuneInString(s)
+			s = s[wid:]
+			if r == '`' {
+				w.addToken(Token{
+					V: b.String(),
+					T: TokenCmdsub,
+					Q: quoted,
+				})
+				return s, nil
+			}
+			if r == '\\' && len(s) > 0 &&
+				strings.ContainsRune("$`\\", rune(s[0])) {
+				r = rune(s[0])
+				s = s[1:]
+			}
+			b.WriteRune(r)
+		}
+		b.WriteRune('\n')
+		s, err = srcin("> ")
+		if err != nil {
+			if err == io.EOF {
+				return "", errMissingEndBackquote
+			}
+			return "", err
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package shellutils
+
+import (
+	"errors"
+	"fmt"
+)
+
+var errNoCmdsub = errors.New("command substitution not supported")
+
+// Expander provides the variable values and command output that replace
+// the corresponding Tokens when a Word is expanded.
+type Expander interface {
+	// Getenv returns the value of the named variable.
+	Getenv(string) string
+	// Cmdsub runs the command list and returns its output with
+	// trailing newlines removed.
+	Cmdsub(string) (string, error)
+}
+
+// Paramser is an Expander with positional parameters that "$@" and "$*"
+// expand to as separate fields.
+type Paramser interface {
+	Params() []string
+}
+
+// Nounseter is an Expander that may fail the expansion of unset variables,
+// e.g. with "set -u".
+type Nounseter interface {
+	// Nounset returns true if expanding an unset variable is an error.
+	Nounset() bool
+	// LookupEnv returns the value of the named variable and whether
+	// it's set.
+	LookupEnv(string) (string, bool)
+}
+
+// UnboundError is that of expanding an unset variable with Nounset.
+type UnboundError struct {
+	Name string
+}
+
+func (err *UnboundError) Error() string {
+	return err.Name + ": unbound variable"
+}
+
+// NullError is that of expanding ${NAME:?WORD} with NAME unset or empty.
+type NullError struct {
+	Name, Msg string
+}
+
+func (err *NullError) Error() string {
+	return err.Name + ": " + err.Msg
+}
+
+// Getenv is an Expander of variables that doesn't support command
+// substitution, e.g. Getenv(os.Getenv).
+type Getenv func(string) string
+
+func (f Getenv) Getenv(k string) string { return f(k) }
+
+func (Getenv) Cmdsub(string) (string, error) { return "", errNoCmdsub }
+
+func (t *Token) expand(e Expander) (string, error) {
+	switch t.T {
+	case TokenLiteral, TokenEnvset:
+		return t.V, nil
+	case TokenEnvget:
+		if len(t.Op) > 0 {
+			return t.expandParam(e)
+		}
+		return getenv(e, t.V)
+	case TokenCmdsub:
+		return e.Cmdsub(t.V)
+	case TokenArith:
+		return t.expandArith(e)
+	}
+	return "", fmt.Errorf("Unknown Token %v", *t)
+}
+
+// getenv returns the value of the named variable; or an error if it's unset
+// and the Expander is a Nounseter that doesn't allow that.
+func getenv(e Expander, k string) (string, error) {
+	if n, ok := e.(Nounseter); ok && n.Nounset() {
+		v, set := n.LookupEnv(k)
+		if !set {
+			return "", &UnboundError{k}
+		}
+		return v, nil
+	}
+	return e.Getenv(k), nil
+}
@@ -0,0 +1,140 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package shellutils
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// Glob returns the sorted names of existing files matching the shell
+// pattern, or nil if there are none. As with Match, a backslash escapes
+// the following character. Unlike Match, the pattern is matched one path
+// component at a time and names beginning with '.' must be matched by a
+// literal '.'.
+func Glob(pattern string) []string {
+	dirs := []string{""}
+	if strings.HasPrefix(pattern, "/") {
+		dirs[0] = "/"
+		pattern = strings.TrimLeft(pattern, "/")
+	}
+	comps := strings.Split(pattern, "/")
+	for i, comp := range comps {
+		var next []string
+		last := i == len(comps)-1
+		switch {
+		case len(comp) == 0:
+			// a trailing slash only matches directories
+			for _, dir := range dirs {
+				if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+					next = append(next, dir+"/")
+				}
+			}
+		case !hasMeta(comp):
+			name := unescape(comp)
+			for _, dir := range dirs {
+				fn := join(dir, name)
+				if _, err := os.Lstat(fn); err == nil {
+					next = append(next, fn)
+				}
+			}
+		default:
+			for _, dir := range dirs {
+				next = append(next, globDir(dir, comp)...)
+			}
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		if !last {
+			sort.Strings(next)
+		}
+		dirs = next
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+func globDir(dir, comp string) []string {
+	var matches []string
+	fn := dir
+	if len(fn) == 0 {
+		fn = "."
+	}
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil
+	}
+	names, _ := f.Readdirnames(-1)
+	f.Close()
+	for _, name := range names {
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(comp, ".") {
+			continue
+		}
+		if match, err := Match(comp, name); err == nil && match {
+			matches = append(matches, join(dir, name))
+		}
+	}
+	return matches
+}
+
+func join(dir, name string) string {
+	if len(dir) == 0 {
+		return name
+	}
+	if strings.HasSuffix(dir, "/") {
+		return dir + name
+	}
+	return dir + "/" + name
+}
+
+// hasMeta reports whether s contains an unescaped '*', '?', or '[' that is
+// followed by a closing ']'.
+func hasMeta(s string) bool {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '*', '?':
+			return true
+		case '[':
+			if i+2 < len(s) && strings.IndexByte(s[i+2:], ']') >= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// escapeMeta returns s with its pattern characters escaped.
+func escapeMeta(s string) string {
+	if !strings.ContainsAny(s, "*?[\\") {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune("*?[\\", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// unescape returns s with the escaping backslashes removed.
+func unescape(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -5,14 +5,19 @@ package shellutils
 
 // List is a slice of pipelines. The pipelines were concatenated via
 // unconditional execution operators (; and &) or conditional
-// execution operators (|| and &&).
+// execution operators (|| and &&). Pos is that of the first Cmdline.
 type List struct {
 	Cmds []Cmdline
+	Pos  Pos
 }
 
 func (ls *List) add(cl *Cmdline) {
+	if len(cl.Cmds) == 0 {
+		cl.Pos = cl.Term.Pos
+	}
 	if ls.Cmds == nil {
 		ls.Cmds = make([]Cmdline, 0)
+		ls.Pos = cl.Pos
 	}
 	ls.Cmds = append(ls.Cmds, *cl)
 	*cl = Cmdline{}
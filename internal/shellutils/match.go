@@ -0,0 +1,131 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package shellutils
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+var errBadPattern = errors.New("syntax error in pattern")
+
+// Match reports whether s matches the shell pattern. Unlike path.Match,
+// '*' and '?' also match '/'. The pattern syntax is:
+//
+//	'*'	matches any sequence of characters
+//	'?'	matches any single character
+//	[...]	matches any single character in the set, which may include
+//		ranges such as a-z; a leading '!' or '^' negates the set
+//	\c	matches character c
+func Match(pattern, s string) (bool, error) {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true, nil
+			}
+			for i := 0; i <= len(s); {
+				if ok, err := Match(pattern, s[i:]); ok || err != nil {
+					return ok, err
+				}
+				if i == len(s) {
+					break
+				}
+				_, wid := utf8.DecodeRuneInString(s[i:])
+				i += wid
+			}
+			return false, nil
+		case '?':
+			if len(s) == 0 {
+				return false, nil
+			}
+			_, wid := utf8.DecodeRuneInString(s)
+			s = s[wid:]
+			pattern = pattern[1:]
+		case '[':
+			if len(s) == 0 {
+				return false, nil
+			}
+			r, wid := utf8.DecodeRuneInString(s)
+			s = s[wid:]
+			ok, rest, err := matchClass(pattern[1:], r)
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				return false, nil
+			}
+			pattern = rest
+		case '\\':
+			pattern = pattern[1:]
+			if len(pattern) == 0 {
+				return false, errBadPattern
+			}
+			fallthrough
+		default:
+			pr, pwid := utf8.DecodeRuneInString(pattern)
+			if len(s) == 0 {
+				return false, nil
+			}
+			r, wid := utf8.DecodeRuneInString(s)
+			if r != pr {
+				return false, nil
+			}
+			s = s[wid:]
+			pattern = pattern[pwid:]
+		}
+	}
+	return len(s) == 0, nil
+}
+
+// matchClass matches r with the set at the beginning of pattern, which
+// follows the opening '['. It returns the remainder of the pattern following
+// the closing ']'.
+func matchClass(pattern string, r rune) (bool, string, error) {
+	negate := false
+	if len(pattern) > 0 && (pattern[0] == '!' || pattern[0] == '^') {
+		negate = true
+		pattern = pattern[1:]
+	}
+	matched := false
+	for first := true; ; first = false {
+		if len(pattern) == 0 {
+			return false, "", errBadPattern
+		}
+		if pattern[0] == ']' && !first {
+			return matched != negate, pattern[1:], nil
+		}
+		lo, wid := classRune(pattern)
+		if wid == 0 {
+			return false, "", errBadPattern
+		}
+		pattern = pattern[wid:]
+		hi := lo
+		if len(pattern) > 1 && pattern[0] == '-' && pattern[1] != ']' {
+			hi, wid = classRune(pattern[1:])
+			if wid == 0 {
+				return false, "", errBadPattern
+			}
+			pattern = pattern[1+wid:]
+		}
+		if lo <= r && r <= hi {
+			matched = true
+		}
+	}
+}
+
+func classRune(pattern string) (rune, int) {
+	if pattern[0] == '\\' {
+		if len(pattern) < 2 {
+			return 0, 0
+		}
+		r, wid := utf8.DecodeRuneInString(pattern[1:])
+		return r, 1 + wid
+	}
+	return utf8.DecodeRuneInString(pattern)
+}
@@ -0,0 +1,346 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package shellutils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	errBadSubstitution = errors.New("bad substitution")
+	errMissingEndBrace = errors.New("Unexpected EOF while looking for matching `}'")
+)
+
+// Setenver is an Expander that may assign variables with ${NAME:=WORD}.
+type Setenver interface {
+	Setenv(string, string)
+}
+
+// parseParam adds the parameter expansion that follows "${" to the Word and
+// returns the remaining input. These are the supported expansions.
+//
+//	${NAME}			the value of NAME
+//	${NAME:-WORD}		WORD if NAME is unset or empty, otherwise its value
+//	${NAME:=WORD}		as :-, also assigning WORD to NAME
+//	${NAME:?[WORD]}		an error with message WORD if NAME is unset or empty
+//	${NAME:+WORD}		WORD if NAME is set and not empty, otherwise empty
+//	${#NAME}		the number of characters in the value of NAME
+//	${NAME#PATTERN}		the value less the shortest matching prefix
+//	${NAME##PATTERN}	the value less the longest matching prefix
+//	${NAME%PATTERN}		the value less the shortest matching suffix
+//	${NAME%%PATTERN}	the value less the longest matching suffix
+//	${NAME/PATTERN/WORD}	the value with the first longest match replaced
+//	${NAME//PATTERN/WORD}	the value with every match replaced
+//
+// The NAME of a braced expansion may have a subscript, e.g. PIPESTATUS[1],
+// that the Expander looks up with the rest of the name.
+func (w *Word) parseParam(s string, srcin func(string) (string, error), quoted bool) (string, error) {
+	t := Token{T: TokenEnvget, Q: quoted}
+	if strings.HasPrefix(s, "#") && len(s) > 1 && s[1] != '}' {
+		t.Op = "length"
+		s = s[1:]
+	}
+	t.V, s = paramName(s)
+	if len(t.V) == 0 {
+		return "", errBadSubstitution
+	}
+	if strings.HasPrefix(s, "[") {
+		i := strings.IndexByte(s, ']')
+		if i < 2 {
+			return "", errBadSubstitution
+		}
+		t.V += s[:i+1]
+		s = s[i+1:]
+	}
+	if len(t.Op) > 0 {
+		if !strings.HasPrefix(s, "}") {
+			return "", errBadSubstitution
+		}
+		w.addToken(t)
+		return s[1:], nil
+	}
+	for _, op := range []string{
+		":-", ":=", ":?", ":+", "##", "#", "%%", "%", "//", "/", "}",
+	} {
+		if strings.HasPrefix(s, op) {
+			t.Op = op
+			s = s[len(op):]
+			break
+		}
+	}
+	switch t.Op {
+	case "}":
+		t.Op = ""
+		w.addToken(t)
+		return s, nil
+	case "":
+		if len(s) == 0 {
+			return "", errMissingEndBrace
+		}
+		return "", errBadSubstitution
+	case "/", "//":
+		var (
+			pattern Word
+			err     error
+		)
+		pattern, s, err = parseOperand(s, srcin, quoted, "/}")
+		if err != nil {
+			return "", err
+		}
+		t.Args = append(t.Args, pattern)
+		if !strings.HasPrefix(s, "/") {
+			t.Args = append(t.Args, Word{})
+			break
+		}
+		s = s[1:]
+		fallthrough
+	default:
+		var (
+			arg Word
+			err error
+		)
+		arg, s, err = parseOperand(s, srcin, quoted, "}")
+		if err != nil {
+			return "", err
+		}
+		t.Args = append(t.Args, arg)
+	}
+	w.addToken(t)
+	return s[1:], nil
+}
+
+// paramName returns the special, positional, or variable name at the
+// beginning of s and the remainder.
+func paramName(s string) (string, string) {
+	if len(s) == 0 {
+		return "", s
+	}
+	if strings.ContainsRune("@*#?$!-", rune(s[0])) {
+		return s[:1], s[1:]
+	}
+	i := 0
+	for i < len(s) {
+		r, wid := utf8.DecodeRuneInString(s[i:])
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		i += wid
+	}
+	return s[:i], s[i:]
+}
+
+// parseOperand returns the Word preceding any of the stop characters, which
+// may include quoted text and nested expansions, and the remaining input
+// beginning with the stop character. Without stop characters, the Word is
+// the whole input.
+func parseOperand(s string, srcin func(string) (string, error), quoted bool, stop string) (Word, string, error) {
+	var (
+		w   Word
+		err error
+	)
+	dq := quoted
+	literal := func(s string) {
+		if dq {
+			w.addQuotedLiteral(s)
+		} else {
+			w.addLiteral(s)
+		}
+	}
+	for {
+		for len(s) > 0 {
+			r, wid := utf8.DecodeRuneInString(s)
+			if !dq || quoted {
+				if strings.ContainsRune(stop, r) {
+					return w, s, nil
+				}
+			}
+			s = s[wid:]
+			switch {
+			case r == '\\' && len(s) > 0:
+				r, wid = utf8.DecodeRuneInString(s)
+				if dq && !strings.ContainsRune("$`\"\\}", r) {
+					w.addQuotedLiteral("\\")
+					continue
+				}
+				s = s[wid:]
+				w.addQuotedLiteral(string(r))
+			case r == '\'' && !dq:
+				i := strings.IndexByte(s, '\'')
+				if i < 0 {
+					return w, "", errMissingEndQuote
+				}
+				w.addQuotedLiteral(s[:i])
+				s = s[i+1:]
+			case r == '"' && !quoted:
+				dq = !dq
+			case r == '$' && len(s) > 0:
+				s, err = w.parseDollar(s, srcin, dq)
+			case r == '`':
+				s, err = w.parseBackquote(s, srcin, dq)
+			default:
+				literal(string(r))
+			}
+			if err != nil {
+				return w, "", err
+			}
+		}
+		if srcin == nil {
+			if len(stop) == 0 {
+				return w, "", nil
+			}
+			return w, "", errMissingEndBrace
+		}
+		literal("\n")
+		s, err = srcin("> ")
+		if err != nil {
+			if err == io.EOF {
+				err = errMissingEndBrace
+			}
+			return w, "", err
+		}
+	}
+}
+
+// expandParam returns the value of the parameter expansion Token.
+func (t *Token) expandParam(e Expander) (string, error) {
+	var (
+		v   string
+		err error
+	)
+	switch t.Op {
+	case ":-", ":=", ":?", ":+":
+		v = e.Getenv(t.V)
+	default:
+		if v, err = getenv(e, t.V); err != nil {
+			return "", err
+		}
+	}
+	switch t.Op {
+	case "length":
+		return strconv.Itoa(utf8.RuneCountInString(v)), nil
+	case ":-", ":=", ":?":
+		if len(v) > 0 {
+			return v, nil
+		}
+		arg, err := t.Args[0].Expand(e)
+		if err != nil {
+			return "", err
+		}
+		switch t.Op {
+		case ":=":
+			m, ok := e.(Setenver)
+			if !ok || !isName(t.V) {
+				return "", fmt.Errorf("$%s: cannot assign in this way",
+					t.V)
+			}
+			m.Setenv(t.V, arg)
+		case ":?":
+			if len(arg) == 0 {
+				arg = "parameter null or not set"
+			}
+			return "", &NullError{t.V, arg}
+		}
+		return arg, nil
+	case ":+":
+		if len(v) == 0 {
+			return "", nil
+		}
+		return t.Args[0].Expand(e)
+	}
+	pattern, err := t.Args[0].Pattern(e)
+	if err != nil {
+		return "", err
+	}
+	switch t.Op {
+	case "#", "##":
+		for _, i := range runeIndexes(v, t.Op == "##") {
+			ok, err := Match(pattern, v[:i])
+			if err != nil {
+				return "", err
+			}
+			if ok {
+				return v[i:], nil
+			}
+		}
+		return v, nil
+	case "%", "%%":
+		for _, i := range runeIndexes(v, t.Op == "%") {
+			ok, err := Match(pattern, v[i:])
+			if err != nil {
+				return "", err
+			}
+			if ok {
+				return v[:i], nil
+			}
+		}
+		return v, nil
+	}
+	repl, err := t.Args[1].Expand(e)
+	if err != nil {
+		return "", err
+	}
+	s := ""
+	for i := 0; i < len(v); {
+		n := 0
+		for _, end := range runeIndexes(v[i:], true) {
+			if end == 0 {
+				break
+			}
+			ok, err := Match(pattern, v[i:i+end])
+			if err != nil {
+				return "", err
+			}
+			if ok {
+				n = end
+				break
+			}
+		}
+		if n == 0 {
+			_, wid := utf8.DecodeRuneInString(v[i:])
+			s += v[i : i+wid]
+			i += wid
+			continue
+		}
+		s += repl
+		i += n
+		if t.Op == "/" {
+			return s + v[i:], nil
+		}
+	}
+	return s, nil
+}
+
+// runeIndexes returns the byte offsets of the rune boundaries of s, from 0
+// through len(s), or the reverse.
+func runeIndexes(s string, reverse bool) []int {
+	indexes := make([]int, 0, len(s)+1)
+	for i := range s {
+		indexes = append(indexes, i)
+	}
+	indexes = append(indexes, len(s))
+	if reverse {
+		for i, j := 0, len(indexes)-1; i < j; i, j = i+1, j-1 {
+			indexes[i], indexes[j] = indexes[j], indexes[i]
+		}
+	}
+	return indexes
+}
+
+// isName returns true if s is a valid variable name.
+func isName(s string) bool {
+	for i, r := range s {
+		if r != '_' && !unicode.IsLetter(r) &&
+			(i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return len(s) > 0
+}
@@ -21,7 +21,42 @@ var errMissingEndQuote = errors.New("Unexpected EOF while looking for matching q
 // Parse calls the srcin function for command input as strings, and
 // return a pointer to a parsed command List, or an error
 func Parse(prompt string, srcin func(string) (string, error)) (*List, error) {
-	s, err := srcin(prompt)
+	var line int
+	return ParseAt(prompt, srcin, &line)
+}
+
+// ParseAt is Parse that counts the lines read from srcin in *line, which is
+// the number of lines read before this call, for the Pos of the parsed Words.
+// Syntax errors are returned as a *SyntaxError.
+func ParseAt(prompt string, srcin func(string) (string, error), line *int) (*List, error) {
+	var (
+		text  string
+		inerr error
+	)
+	read := func(prompt string) (string, error) {
+		s, err := srcin(prompt)
+		if err != nil {
+			inerr = err
+			return s, err
+		}
+		*line++
+		text = s
+		return s, nil
+	}
+	pos := func(s string) Pos {
+		if len(s) > len(text) {
+			return Pos{Line: *line, Column: 1}
+		}
+		return Pos{Line: *line, Column: len(text) - len(s) + 1}
+	}
+	// errors other than those of srcin are syntax errors at the Pos
+	syntax := func(at Pos, err error) error {
+		if err == inerr {
+			return err
+		}
+		return &SyntaxError{Pos: at, Err: err}
+	}
+	s, err := read(prompt)
 	if err != nil {
 		return nil, err
 	}
@@ -31,6 +66,7 @@ func Parse(prompt string, srcin func(string) (string, error)) (*List, error) {
 	inWS := true
 processRune:
 	for len(s) > 0 {
+		at := pos(s)
 		r, wid := utf8.DecodeRuneInString(s)
 		s = s[wid:]
 		if inWS {
@@ -48,19 +84,51 @@ processRune:
 				continue
 			}
 
-			if strings.ContainsRune("|&;()<>", r) {
+			// a descriptor number prefaces the redirection, e.g. 2>
+			if strings.ContainsRune("|&;()<>", r) &&
+				!(strings.ContainsRune("<>", r) && w.isFd()) {
 				c.add(&w)
 			}
 		}
+		if len(w.Tokens) == 0 {
+			w.Pos = at
+		}
+
+		if r == '<' {
+			w.addLiteral("<")
+			for _, op := range []string{"<<", "<-", "<", "&"} {
+				if strings.HasPrefix(s, op) {
+					s = s[len(op):]
+					w.addLiteral(op)
+					break
+				}
+			}
+			c.add(&w)
+			inWS = true
+			continue
+		}
+
+		if r == '&' && strings.HasPrefix(s, ">") {
+			w.addLiteral("&>")
+			s = s[1:]
+			if strings.HasPrefix(s, ">") {
+				s = s[1:]
+				w.addLiteral(">")
+			}
+			c.add(&w)
+			inWS = true
+			continue
+		}
 
-		if strings.ContainsRune("&;()<", r) {
+		if strings.ContainsRune("&;()", r) {
 			w.addLiteral(string(r))
 			// hack - we know these are single-byte runes
 			if len(s) >= 1 && s[0] == byte(r) {
 				s = s[1:]
 				w.addLiteral(string(r))
 			}
-			if w.String() == ";" || w.String() == "&&" ||
+			if w.String() == ";" || w.String() == ";;" ||
+				w.String() == "&" || w.String() == "&&" ||
 				w.String() == "||" {
 				c.Term = w
 				w = Word{}
@@ -99,16 +167,27 @@ processRune:
 		}
 
 		if r == '$' && len(s) > 0 {
-			s, err = w.parseEnv(s)
+			s, err = w.parseDollar(s, read, false)
 			if err != nil {
-				return nil, err
+				return nil, syntax(at, err)
+			}
+			continue
+		}
+
+		if r == '`' {
+			s, err = w.parseBackquote(s, read, false)
+			if err != nil {
+				return nil, syntax(at, err)
 			}
 			continue
 		}
 
 		if r == '>' {
 			w.addLiteral(">")
-			if len(s) >= 1 && s[0] == '>' {
+			if len(s) >= 1 && s[0] == '&' {
+				s = s[1:]
+				w.addLiteral("&")
+			} else if len(s) >= 1 && s[0] == '>' {
 				s = s[1:]
 				w.addLiteral(">")
 				if len(s) >= 1 && s[0] == '>' {
@@ -133,13 +212,13 @@ processRune:
 					if r == '\'' {
 						continue processRune
 					}
-					w.addLiteral(string(r))
+					w.addQuotedLiteral(string(r))
 				}
-				w.addLiteral("\n")
-				s, err = srcin("> ")
+				w.addQuotedLiteral("\n")
+				s, err = read("> ")
 				if err != nil {
 					if err == io.EOF {
-						return nil, errMissingEndQuote
+						return nil, syntax(at, errMissingEndQuote)
 					}
 					return nil, err
 				}
@@ -155,36 +234,49 @@ processRune:
 					}
 
 					if r == '$' && len(s) > 0 {
-						s, err = w.parseEnv(s)
+						at := pos(s)
+						at.Column--
+						s, err = w.parseDollar(s, read, true)
 						if err != nil {
-							return nil, err
+							return nil, syntax(at, err)
+						}
+						continue
+					}
+					if r == '`' {
+						at := pos(s)
+						at.Column--
+						s, err = w.parseBackquote(s, read,
+							true)
+						if err != nil {
+							return nil, syntax(at, err)
 						}
 						continue
 					}
 					if r == '\\' {
 						if len(s) == 0 {
-							s, err = srcin("> ")
+							s, err = read("> ")
 							if err != nil {
 								if err == io.EOF {
-									return nil, errMissingEndQuote
+									return nil, syntax(at, errMissingEndQuote)
 								}
 								return nil, err
 							}
 							continue
 						}
 						r1, wid := utf8.DecodeRuneInString(s)
-						if r1 == '$' || r1 == '"' || r1 == '\\' {
+						if r1 == '$' || r1 == '`' || r1 == '"' ||
+							r1 == '\\' {
 							r = r1
 							s = s[wid:]
 						}
 					}
-					w.addLiteral(string(r))
+					w.addQuotedLiteral(string(r))
 				}
-				w.addLiteral("\n")
-				s, err = srcin("> ")
+				w.addQuotedLiteral("\n")
+				s, err = read("> ")
 				if err != nil {
 					if err == io.EOF {
-						return nil, errMissingEndQuote
+						return nil, syntax(at, errMissingEndQuote)
 					}
 					return nil, err
 				}
@@ -194,10 +286,10 @@ processRune:
 			if len(s) > 0 {
 				r, wid := utf8.DecodeRuneInString(s)
 				s = s[wid:]
-				w.addLiteral(string(r))
+				w.addQuotedLiteral(string(r))
 				continue
 			}
-			s, err = srcin("... ")
+			s, err = read("... ")
 			if err != nil {
 				return nil, err
 			}
@@ -0,0 +1,33 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package shellutils
+
+import "fmt"
+
+// Pos is the source position of a parsed Word, Cmdline, or List. Line counts
+// from 1 with the lines read from srcin; Column is the byte offset, from 1,
+// within that line. The zero Pos is unknown.
+type Pos struct {
+	Line, Column int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Errorf returns a SyntaxError at this position.
+func (p Pos) Errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Pos: p, Err: fmt.Errorf(format, args...)}
+}
+
+// SyntaxError is a parse error of the input at Pos.
+type SyntaxError struct {
+	Pos
+	Err error
+}
+
+func (err *SyntaxError) Error() string {
+	return fmt.Sprint(err.Pos, ": ", err.Err)
+}
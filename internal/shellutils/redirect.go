@@ -0,0 +1,165 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package shellutils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Redirect is an i/o redirection of a command's file descriptor, Fd, by
+// one of these operators.
+//
+//	<	open Target for reading
+//	<<	read a here document up to the line with label Target
+//	<<-	as above, with leading whitespace trimmed from each line
+//	<<<	read the here string Target followed by a newline
+//	<&	duplicate the descriptor Target
+//	>	create Target for writing
+//	>>	append to Target
+//	>>>	write to both the descriptor and Target
+//	>>>>	write to both the descriptor and the end of Target
+//	>&	duplicate the descriptor Target
+//	&>	create Target for both stdout and stderr, Fd is -1
+//	&>>	append both stdout and stderr to Target, Fd is -1
+type Redirect struct {
+	Fd     int
+	Op     string
+	Target string
+}
+
+var redirectOps = []string{
+	"<<<", "<<-", "<<", "<&", "<",
+	">>>>", ">>>", ">>", ">&", ">",
+}
+
+// isFd returns true if the Word is an unquoted descriptor number that may
+// preface a redirection operator, e.g. 2>.
+func (w *Word) isFd() bool {
+	if len(w.Tokens) != 1 || w.Tokens[0].T != TokenLiteral ||
+		w.Tokens[0].Q {
+		return false
+	}
+	return len(strings.Trim(w.Tokens[0].V, "0123456789")) == 0
+}
+
+// redirect returns the descriptor and operator of an unquoted redirection
+// Word, or false if it isn't one.
+func (w *Word) redirect() (int, string, bool) {
+	if len(w.Tokens) != 1 || w.Tokens[0].T != TokenLiteral ||
+		w.Tokens[0].Q {
+		return 0, "", false
+	}
+	s := w.Tokens[0].V
+	if s == "&>" || s == "&>>" {
+		return -1, s, true
+	}
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	for _, op := range redirectOps {
+		if s[i:] != op {
+			continue
+		}
+		fd := 0
+		if op[0] == '>' {
+			fd = 1
+		}
+		if i > 0 {
+			n, err := strconv.Atoi(s[:i])
+			if err != nil {
+				return 0, "", false
+			}
+			fd = n
+		}
+		return fd, op, true
+	}
+	return 0, "", false
+}
+
+// SliceRedirects is Slice that removes the redirection operators and their
+// targets from the command, returning them separately in order. The targets
+// are expanded without field splitting or pathname expansion; and may be
+// separated from the operator by '=' rather than space, e.g. >=URL.
+func (c *Cmdline) SliceRedirects(e Expander) (map[string]string, []string, []Redirect, error) {
+	var (
+		redirects []Redirect
+		words     []Word
+	)
+	for i := 0; i < len(c.Cmds); i++ {
+		fd, op, ok := c.Cmds[i].redirect()
+		if !ok {
+			words = append(words, c.Cmds[i])
+			continue
+		}
+		i++
+		if i == len(c.Cmds) {
+			return nil, nil, nil, fmt.Errorf("%s: missing target", op)
+		}
+		target := c.Cmds[i]
+		if len(target.Tokens) > 1 && target.Tokens[0].T == TokenEnvset {
+			target.Tokens = target.Tokens[1:]
+		}
+		s := target.String()
+		if !strings.HasPrefix(op, "<<") || op == "<<<" {
+			var err error
+			s, err = target.Expand(e)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		redirects = append(redirects, Redirect{
+			Fd:     fd,
+			Op:     op,
+			Target: s,
+		})
+	}
+	cl := Cmdline{Cmds: words, Term: c.Term}
+	envmap, args, err := cl.Slice(e)
+	return envmap, args, redirects, err
+}
+
+// IsRedirects returns true if the words of the command line that follow
+// the first n are redirections, e.g. those after the "done" of a loop.
+func (c *Cmdline) IsRedirects(n int) bool {
+	for i := n; i < len(c.Cmds); i += 2 {
+		if _, _, ok := c.Cmds[i].redirect(); !ok || i+1 == len(c.Cmds) {
+			return false
+		}
+	}
+	return true
+}
+
+// Heredocs returns the here document redirections of the List in order, with
+// the unexpanded label as Target, e.g. for a reader to skip their documents
+// without running the commands.
+func (ls *List) Heredocs() []Redirect {
+	var redirects []Redirect
+	for _, cl := range ls.Cmds {
+		for i := 0; i < len(cl.Cmds)-1; i++ {
+			fd, op, ok := cl.Cmds[i].redirect()
+			if !ok {
+				continue
+			}
+			i++
+			if op != "<<" && op != "<<-" {
+				continue
+			}
+			target := cl.Cmds[i]
+			if len(target.Tokens) > 1 &&
+				target.Tokens[0].T == TokenEnvset {
+				target.Tokens = target.Tokens[1:]
+			}
+			redirects = append(redirects, Redirect{
+				Fd:     fd,
+				Op:     op,
+				Target: target.String(),
+			})
+		}
+	}
+	return redirects
+}
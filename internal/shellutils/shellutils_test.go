@@ -7,7 +7,10 @@ package shellutils
 import (
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -36,17 +39,19 @@ func testSlice(script []string) (*List, error) {
 }
 
 func (ls *List) print() {
-	for _, pl := range ls.Cmds {
-		for _, sl := range pl.Cmds {
-			_, cmdline := sl.Slice(os.Getenv)
-			term := sl.Term.String()
-			if term == "" {
-				term = "\n"
-			} else {
-				term = " " + term + " "
-			}
-			fmt.Print(strings.Join(cmdline, " "), term)
+	for _, cl := range ls.Cmds {
+		_, cmdline, err := cl.Slice(Getenv(os.Getenv))
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		term := cl.Term.String()
+		if term == "" {
+			term = "\n"
+		} else {
+			term = " " + term + " "
 		}
+		fmt.Print(strings.Join(cmdline, " "), term)
 	}
 }
 
@@ -98,6 +103,25 @@ func TestBoolean(t *testing.T) {
 	cmd.print()
 }
 
+func TestBackground(t *testing.T) {
+	script := []string{"sleep 1 & wait && echo done"}
+
+	ls, err := testSlice(script)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var terms []string
+	for _, cl := range ls.Cmds {
+		terms = append(terms, cl.Term.String())
+	}
+	if got, want := strings.Join(terms, ","), "&,&&,"; got != want {
+		t.Errorf("terms %q, want %q", got, want)
+	}
+	ls.print()
+}
+
 // TestDoublequote: The backslash retains its special meaning only when followed by one of the following characters:
 // ‘$’, ‘`’, ‘"’, ‘\’, or newline.
 func TestDoublequote(t *testing.T) {
@@ -111,3 +135,544 @@ func TestDoublequote(t *testing.T) {
 
 	cmd.print()
 }
+
+func TestCaseItems(t *testing.T) {
+	script := []string{"case $x in a|b) echo ab;; *) echo other;; esac"}
+
+	ls, err := testSlice(script)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var terms []string
+	for _, cl := range ls.Cmds {
+		terms = append(terms, cl.Term.String())
+	}
+	if got, want := strings.Join(terms, ","), "|,;;,;;,"; got != want {
+		t.Errorf("terms %q, want %q", got, want)
+	}
+	ls.print()
+}
+
+func TestMatch(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, s string
+		match      bool
+	}{
+		{"*", "", true},
+		{"*", "a/b", true},
+		{"platina-mk2-*", "platina-mk2-lc1-bmc", true},
+		{"platina-mk2-*", "platina-mk1", false},
+		{"?", "", false},
+		{"a?c", "abc", true},
+		{"[abc]", "b", true},
+		{"[!abc]", "b", false},
+		{"[^a-c]x", "dx", true},
+		{"[a-c]*", "cat", true},
+		{"[]]", "]", true},
+		{"\\*", "*", true},
+		{"\\*", "a", false},
+		{"*.log", "goes.log", true},
+		{"*.log", "goes.log.1", false},
+	} {
+		match, err := Match(tc.pattern, tc.s)
+		if err != nil {
+			t.Error(tc.pattern, err)
+		} else if match != tc.match {
+			t.Errorf("Match(%q, %q) = %v", tc.pattern, tc.s, match)
+		}
+	}
+	if _, err := Match("[abc", "a"); err == nil {
+		t.Error("expected error for unterminated set")
+	}
+}
+
+type testExpander map[string]string
+
+func (m testExpander) Getenv(k string) string { return m[k] }
+
+func (m testExpander) Cmdsub(s string) (string, error) {
+	return m["$("+s+")"], nil
+}
+
+func TestCmdsub(t *testing.T) {
+	script := []string{
+		"echo $(hget platina eth0.ip) `uname` \"$(ls (x) \")\")\" $(echo",
+		"b)x v=$(echo a  b)",
+	}
+
+	ls, err := testSlice(script)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	e := testExpander{
+		"$(hget platina eth0.ip)": "10.0.0.1",
+		"$(uname)":                "Linux",
+		"$(ls (x) \")\")":         "a  b",
+		"$(echo\nb)":              " c d",
+		"$(echo a  b)":            "1 2",
+	}
+	_, args, err := ls.Cmds[0].Slice(e)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	got := strings.Join(args, ",")
+	want := "echo,10.0.0.1,Linux,a  b,c,dx,v=1,2"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	cl := Cmdline{}
+	cl.Cmds = ls.Cmds[0].Cmds[len(ls.Cmds[0].Cmds)-1:]
+	envmap, args, err := cl.Slice(e)
+	if err != nil {
+		t.Error(err)
+	} else if len(args) != 0 || envmap["v"] != "1 2" {
+		t.Errorf("assignment: %v %v", envmap, args)
+	}
+
+	// the unmatched ')' of case patterns
+	ls, err = testSlice([]string{
+		`echo $(case a in a) echo y;; (b) echo "$(echo z)";; esac) ` +
+			`$(case b in esac) $(echo case) $(case c in`,
+		`c | d) (echo w);;`,
+		`esac; echo x)`,
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	e = testExpander{
+		`$(case a in a) echo y;; (b) echo "$(echo z)";; esac)`: "y",
+		"$(case b in esac)": "",
+		"$(echo case)":      "case",
+		"$(case c in\nc | d) (echo w);;\nesac; echo x)": "w x",
+	}
+	_, args, err = ls.Cmds[0].Slice(e)
+	if err != nil {
+		t.Error(err)
+	} else if got, want := strings.Join(args, ","),
+		"echo,y,case,w,x"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+type testParamser struct {
+	testExpander
+	params []string
+}
+
+func (p testParamser) Params() []string { return p.params }
+
+func TestParams(t *testing.T) {
+	script := []string{`echo $# $10 "$@" x$@y "$*" $*`}
+
+	ls, err := testSlice(script)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	e := testParamser{
+		testExpander: testExpander{"#": "2", "1": "a b", "*": "a b c"},
+		params:       []string{"a b", "c"},
+	}
+	_, args, err := ls.Cmds[0].Slice(e)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	got := strings.Join(args, ",")
+	want := "echo,2,a b0,a b,c,xa b,cy,a b c,a b,c"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	e.params = nil
+	_, args, err = ls.Cmds[0].Slice(e)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	got = strings.Join(args, ",")
+	want = "echo,2,a b0,xy,a b c"
+	if got != want {
+		t.Errorf("without params, got %q, want %q", got, want)
+	}
+}
+
+type testSetenver struct {
+	testExpander
+}
+
+func (m testSetenver) Setenv(k, v string) { m.testExpander[k] = v }
+
+func TestParamExpansion(t *testing.T) {
+	e := testSetenver{testExpander{
+		"path": "/usr/lib/libc.so.6",
+		"dflt": "x y",
+		"u":    "日本語",
+		"a[1]": "one",
+	}}
+	for _, tc := range []struct{ script, want string }{
+		{`echo ${path} ${#path} ${#u}`, "/usr/lib/libc.so.6,18,3"},
+		{`echo ${unset:-$dflt} "${unset:-'a'}" ${unset:-'a b'}`,
+			"x y,'a',a b"},
+		{`echo ${path:+set} ${unset:+set}x`, "set,x"},
+		{`echo ${path#*/} ${path##*/} ${path%.*} ${path%%.*}`,
+			"usr/lib/libc.so.6,libc.so.6,/usr/lib/libc.so,/usr/lib/libc"},
+		{`echo ${path#"*"/} ${u#日}`, "/usr/lib/libc.so.6,本語"},
+		{`echo ${path/lib/LIB} ${path//lib/LIB} ${path//[.\/]}`,
+			"/usr/LIB/libc.so.6,/usr/LIB/LIBc.so.6,usrliblibcso6"},
+		{`echo ${new:=$dflt} $new`, "x y,x y"},
+		{`echo ${a[1]} ${a[2]:-none}`, "one,none"},
+	} {
+		ls, err := testSlice([]string{tc.script})
+		if err != nil {
+			t.Error(tc.script, err)
+			continue
+		}
+		_, args, err := ls.Cmds[0].Slice(e)
+		if err != nil {
+			t.Error(tc.script, err)
+			continue
+		}
+		if got := strings.Join(args[1:], ","); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.script, got, tc.want)
+		}
+	}
+
+	ls, err := testSlice([]string{`echo ${unset:?"is required"}`})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, _, err = ls.Cmds[0].Slice(e)
+	if err == nil || err.Error() != "unset: is required" {
+		t.Errorf("got %v, want error unset: is required", err)
+	}
+
+	for _, script := range []string{`echo ${x:}`, `echo ${x`, `echo ${}`} {
+		if _, err := testSlice([]string{script}); err == nil {
+			t.Errorf("%s: expected error", script)
+		}
+	}
+}
+
+func TestArith(t *testing.T) {
+	e := testSetenver{testExpander{"i": "5", "mask": "0xf0"}}
+	for _, tc := range []struct {
+		expr string
+		want int64
+	}{
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"0x1f & mask", 0x10},
+		{"1 << 4 | 1", 17},
+		{"-7 / 2", -3},
+		{"2 ** 3 ** 2", 512},
+		{"3 > 2 && 2 <= 1", 0},
+		{"i == 5 ? 010 : 0", 8},
+		{"!i + ~0", -1},
+		{"0 && 1 / 0", 0},
+		{"i++", 5},
+		{"++i", 7},
+		{"i += 3, i * 2", 20},
+		{"unset + 1", 1},
+	} {
+		v, err := Arith(tc.expr, e)
+		if err != nil {
+			t.Error(tc.expr, err)
+		} else if v != tc.want {
+			t.Errorf("%s: got %d, want %d", tc.expr, v, tc.want)
+		}
+	}
+	if e.testExpander["i"] != "10" {
+		t.Errorf("i: got %q, want 10", e.testExpander["i"])
+	}
+	for _, expr := range []string{"1 / 0", "1 +", "(1", "1 2", "0x", "++1"} {
+		if _, err := Arith(expr, e); err == nil {
+			t.Errorf("%s: expected error", expr)
+		}
+	}
+
+	ls, err := testSlice([]string{`echo $((i+1))x "$(( $i * 2 ))"`})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, args, err := ls.Cmds[0].Slice(e)
+	if err != nil {
+		t.Error(err)
+	} else if got, want := strings.Join(args, ","), "echo,11x,20"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// unbraced names end at the first character that can't be in one
+	ls, err = testSlice([]string{
+		`echo $(($i+1)) $((i-$i*2)) "[$i]" $i/x "$i.$i-" $i_ $%`,
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, args, err = ls.Cmds[0].Slice(e)
+	if err != nil {
+		t.Error(err)
+	} else if got, want := strings.Join(args, ","),
+		"echo,11,-10,[10],10/x,10.10-,,$%"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+type testNounseter struct {
+	testExpander
+}
+
+func (testNounseter) Nounset() bool { return true }
+
+func (m testNounseter) LookupEnv(k string) (string, bool) {
+	v, set := m.testExpander[k]
+	return v, set
+}
+
+func TestNounset(t *testing.T) {
+	e := testNounseter{testExpander{"set": "x", "empty": ""}}
+	for _, tc := range []struct {
+		script string
+		ok     bool
+	}{
+		{`echo $set "$empty" ${#set}`, true},
+		{`echo ${unset:-y} ${unset:+y}`, true},
+		{`echo $((set + 1))`, false},
+		{`echo $((0 && unset))`, true},
+		{`echo $unset`, false},
+		{`echo "${unset}"`, false},
+		{`echo ${#unset}`, false},
+		{`echo ${unset%.*}`, false},
+		{`echo $((unset + 1))`, false},
+	} {
+		ls, err := testSlice([]string{tc.script})
+		if err != nil {
+			t.Error(tc.script, err)
+			continue
+		}
+		_, _, err = ls.Cmds[0].Slice(e)
+		if tc.ok && err != nil {
+			t.Error(tc.script, err)
+		} else if !tc.ok && err == nil {
+			t.Errorf("%s: expected error", tc.script)
+		}
+	}
+}
+
+func TestSource(t *testing.T) {
+	for _, tc := range []struct{ script, want string }{
+		{`echo $x "$y"'s' \$z`, `echo ${x} "${y}"'s' '$'z`},
+		{`a=1 b="it's" cmd && next`, `a=1 b='it'\''s' cmd &&`},
+		{`echo "${x:-a $y}" ${#x} ${x//a/b}`,
+			`echo "${x:-a ${y}}" ${#x} ${x//a/b}`},
+		{`echo $(date) "$((i + 1))" > out;`,
+			`echo $(date) "$((i + 1))" > out;`},
+	} {
+		ls, err := testSlice([]string{tc.script})
+		if err != nil {
+			t.Error(tc.script, err)
+			continue
+		}
+		got := ls.Cmds[0].Source()
+		if got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.script, got, tc.want)
+			continue
+		}
+		again, err := testSlice([]string{got})
+		if err != nil {
+			t.Error(got, err)
+		} else if s := again.Cmds[0].Source(); s != got {
+			t.Errorf("%s: reparsed as %q", got, s)
+		}
+	}
+}
+
+func TestPos(t *testing.T) {
+	script := []string{
+		"echo a  'b c",
+		"d' | wc -l; x=$(date)",
+	}
+	ls, err := testSlice(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, cl := range ls.Cmds {
+		got = append(got, cl.Pos.String())
+		for _, w := range cl.Cmds {
+			got = append(got, w.Pos.String())
+		}
+		if len(cl.Term.Tokens) > 0 {
+			got = append(got, cl.Term.Pos.String())
+		}
+	}
+	want := "1:1,1:1,1:6,1:9,2:4,2:6,2:6,2:9,2:11,2:13,2:13"
+	if s := strings.Join(got, ","); s != want {
+		t.Errorf("got %s, want %s", s, want)
+	}
+	if s := ls.Pos.String(); s != "1:1" {
+		t.Errorf("list at %s, want 1:1", s)
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	for _, tc := range []struct {
+		script []string
+		want   string
+	}{
+		{[]string{"echo 'a", "b"}, "1:6: " + errMissingEndQuote.Error()},
+		{[]string{`echo x "$(date`}, "1:9: " + errMissingEndParen.Error()},
+		{[]string{"echo ${x"}, "1:6: " + errMissingEndBrace.Error()},
+	} {
+		lines := tc.script
+		line := 10
+		_, err := ParseAt(">", func(string) (string, error) {
+			if len(lines) == 0 {
+				return "", io.EOF
+			}
+			s := lines[0]
+			lines = lines[1:]
+			return s, nil
+		}, &line)
+		serr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("%q: %v isn't a SyntaxError", tc.script, err)
+			continue
+		}
+		serr.Line -= 10
+		if s := serr.Error(); s != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.script, s, tc.want)
+		}
+	}
+}
+
+func TestHeredocs(t *testing.T) {
+	ls, err := testSlice([]string{`cat <<EOF >out; tr a b <<-'END' 2>&1`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range ls.Heredocs() {
+		got = append(got, r.Op+r.Target)
+	}
+	if s, want := strings.Join(got, ","), "<<EOF,<<-END"; s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+}
+
+func TestRedirects(t *testing.T) {
+	script := []string{`cmd 2>err a 2 > out 2>&1 <<<"$x y" &>> all ">" b <<-EOF`}
+
+	ls, err := testSlice(script)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if n := len(ls.Cmds); n != 1 {
+		t.Errorf("got %d command lines, want 1", n)
+		return
+	}
+	_, args, redirects, err := ls.Cmds[0].SliceRedirects(testExpander{
+		"x": "here",
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := strings.Join(args, ","), "cmd,a,2,>,b"; got != want {
+		t.Errorf("args %q, want %q", got, want)
+	}
+	var got []string
+	for _, r := range redirects {
+		got = append(got, fmt.Sprint(r.Fd, r.Op, r.Target))
+	}
+	want := "2>err,1>out,2>&1,0<<<here y,-1&>>all,0<<-EOF"
+	if s := strings.Join(got, ","); s != want {
+		t.Errorf("redirects %q, want %q", s, want)
+	}
+}
+
+func TestIsRedirects(t *testing.T) {
+	for s, want := range map[string]bool{
+		"done":                 true,
+		"done < in 2>&1":       true,
+		"done >":               false,
+		"done x":               false,
+		"done > out x":         false,
+		`done ">" out`:         false,
+		"done <<EOF >> out":    true,
+		"done &> all < /dev/0": true,
+	} {
+		ls, err := testSlice([]string{s})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ls.Cmds[0].IsRedirects(1); got != want {
+			t.Errorf("%q: got %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestGlob(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shellutils")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, fn := range []string{
+		"eth0/address",
+		"eth1/address",
+		"lo/address",
+		".hidden/address",
+		"goes-1.log",
+		"goes-2.log",
+		"*.log",
+	} {
+		fn = filepath.Join(dir, fn)
+		os.MkdirAll(filepath.Dir(fn), 0755)
+		if err = ioutil.WriteFile(fn, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		line, want string
+	}{
+		{"ls DIR/*/address", "ls DIR/eth0/address DIR/eth1/address DIR/lo/address"},
+		{"ls DIR/eth[0-9]/address", "ls DIR/eth0/address DIR/eth1/address"},
+		{"ls DIR/goes-?.log", "ls DIR/goes-1.log DIR/goes-2.log"},
+		{"ls DIR/\\*.log", "ls DIR/*.log"},
+		{"ls 'DIR/*'.log", "ls DIR/*.log"},
+		{"ls \"DIR\"/*.log", "ls DIR/*.log DIR/goes-1.log DIR/goes-2.log"},
+		{"ls DIR/.h*/address", "ls DIR/.hidden/address"},
+		{"ls DIR/none*", "ls DIR/none*"},
+		{"[ -e DIR ]", "[ -e DIR ]"},
+	} {
+		line := strings.Replace(tc.line, "DIR", dir, -1)
+		ls, err := testSlice([]string{line})
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		_, args, err := ls.Cmds[0].Slice(Getenv(os.Getenv))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		got := strings.Replace(strings.Join(args, " "), dir, "DIR", -1)
+		if got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.line, got, tc.want)
+		}
+	}
+}
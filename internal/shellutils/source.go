@@ -0,0 +1,97 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package shellutils
+
+import "strings"
+
+// Source returns the shell input of the Word, which may differ in quotation
+// and braces from that parsed, e.g. "$x"'s' is "${x}"'s'.
+func (w *Word) Source() string {
+	return w.source(false)
+}
+
+// Source returns the shell input of the command line and its terminator.
+func (c *Cmdline) Source() string {
+	words := make([]string, 0, len(c.Cmds))
+	for i := range c.Cmds {
+		words = append(words, c.Cmds[i].Source())
+	}
+	s := strings.Join(words, " ")
+	switch term := c.Term.String(); term {
+	case "":
+	case ";":
+		s += term
+	default:
+		s += " " + term
+	}
+	return s
+}
+
+// Source returns the shell input of the command lines.
+func (ls *List) Source() string {
+	lines := make([]string, 0, len(ls.Cmds))
+	for i := range ls.Cmds {
+		lines = append(lines, ls.Cmds[i].Source())
+	}
+	return strings.Join(lines, " ")
+}
+
+// source returns the shell input of the Word; dq is true within double
+// quotes, where quoted literals are escaped rather than single quoted.
+func (w *Word) source(dq bool) string {
+	s := ""
+	for i := range w.Tokens {
+		s += w.Tokens[i].source(dq)
+	}
+	return s
+}
+
+func (t *Token) source(dq bool) string {
+	var s string
+	switch t.T {
+	case TokenLiteral:
+		switch {
+		case !t.Q:
+			return t.V
+		case dq:
+			return escape(t.V, "$`\"\\}")
+		}
+		return "'" + strings.Replace(t.V, "'", `'\''`, -1) + "'"
+	case TokenEnvset:
+		return t.V
+	case TokenEnvget:
+		switch t.Op {
+		case "":
+			s = "${" + t.V + "}"
+		case "length":
+			s = "${#" + t.V + "}"
+		case "/", "//":
+			s = "${" + t.V + t.Op + t.Args[0].source(t.Q) + "/" +
+				t.Args[1].source(t.Q) + "}"
+		default:
+			s = "${" + t.V + t.Op + t.Args[0].source(t.Q) + "}"
+		}
+	case TokenCmdsub:
+		s = "$(" + t.V + ")"
+	case TokenArith:
+		s = "$((" + t.Args[0].source(true) + "))"
+	}
+	if t.Q && !dq {
+		return `"` + s + `"`
+	}
+	return s
+}
+
+// escape returns s with a backslash before each of the special characters.
+func escape(s, special string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(special, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -12,17 +12,29 @@ package shellutils
 // tokenEnvset is the operator to set an environment variable. The string is
 // the assignment operator, i.e. =. This is represented as a token to prevent
 // quoted = characters to be interpreted as setting environment variables
+// tokenCmdsub is a command substitution, $(...) or `...`. The string is the
+// command list to run, whose output replaces the token.
+// tokenArith is an arithmetic expansion, $((...)). The expression is the
+// first of Args, which is expanded then evaluated.
 type Tokentype int
 
 const (
 	TokenLiteral = iota
 	TokenEnvget
 	TokenEnvset
+	TokenCmdsub
+	TokenArith
 )
 
 // Token is a type and a string value. During parsing, we convert
-// string input into a series of tokens.
+// string input into a series of tokens. Q is true for tokens that were
+// quoted or escaped, so their expansion isn't split into fields.
+// Op and Args are the operator and operand Words of a tokenEnvget
+// parameter expansion such as ${NAME:-WORD}; Op is "length" for ${#NAME}.
 type Token struct {
-	V string
-	T Tokentype
+	V    string
+	T    Tokentype
+	Q    bool
+	Op   string
+	Args []Word
 }
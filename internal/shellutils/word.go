@@ -5,26 +5,33 @@
 package shellutils
 
 import (
-	"errors"
-	"fmt"
 	"strings"
-	"unicode"
 	"unicode/utf8"
 )
 
 // Word is a slice of Tokens. When converting to a string, all of the Tokens
-// are evaluated to produce strings, which are concatenated.
+// are evaluated to produce strings, which are concatenated. Pos is where the
+// Word began in the parsed input.
 
 type Word struct {
 	Tokens []Token
+	Pos    Pos
 }
 
 // add adds a Token to the current Word being parsed
 func (w *Word) add(s string, ty Tokentype) {
+	w.addToken(Token{V: s, T: ty})
+}
+
+// addQuoted adds a quoted Token to the current Word being parsed
+func (w *Word) addQuoted(s string, ty Tokentype) {
+	w.addToken(Token{V: s, T: ty, Q: true})
+}
+
+func (w *Word) addToken(t Token) {
 	if w.Tokens == nil {
 		w.Tokens = make([]Token, 0)
 	}
-	t := Token{V: s, T: ty}
 	w.Tokens = append(w.Tokens, t)
 }
 
@@ -34,7 +41,7 @@ func (w *Word) add(s string, ty Tokentype) {
 func (w *Word) addLiteral(s string) {
 	if len(w.Tokens) > 0 {
 		end := len(w.Tokens) - 1
-		if w.Tokens[end].T == TokenLiteral {
+		if w.Tokens[end].T == TokenLiteral && !w.Tokens[end].Q {
 			w.Tokens[end].V += s
 			return
 		}
@@ -42,35 +49,221 @@ func (w *Word) addLiteral(s string) {
 	w.add(s, TokenLiteral)
 }
 
-func (w *Word) parseEnv(s string) (string, error) {
+// addQuotedLiteral is the addLiteral of quoted or escaped text.
+func (w *Word) addQuotedLiteral(s string) {
+	if len(w.Tokens) > 0 {
+		end := len(w.Tokens) - 1
+		if w.Tokens[end].T == TokenLiteral && w.Tokens[end].Q {
+			w.Tokens[end].V += s
+			return
+		}
+	}
+	w.addQuoted(s, TokenLiteral)
+}
+
+// parseDollar adds the arithmetic expansion, command substitution, or
+// parameter that follows '$' to the Word and returns the remaining input.
+func (w *Word) parseDollar(s string, srcin func(string) (string, error), quoted bool) (string, error) {
+	if strings.HasPrefix(s, "((") {
+		rest, arith, err := w.parseArith(s[2:], quoted)
+		if arith || err != nil {
+			return rest, err
+		}
+	}
+	if strings.HasPrefix(s, "(") {
+		return w.parseCmdsub(s[1:], srcin, quoted)
+	}
+	return w.parseEnv(s, srcin, quoted)
+}
+
+func (w *Word) parseEnv(s string, srcin func(string) (string, error), quoted bool) (string, error) {
 	envvar := ""
+	add := w.add
+	if quoted {
+		add = w.addQuoted
+	}
 	if s[0] == '{' {
+		return w.parseParam(s[1:], srcin, quoted)
+	}
+
+	// special and positional parameters have single character names,
+	// e.g. $10 is $1 followed by 0
+	if strings.ContainsRune("@*#?$!-0123456789", rune(s[0])) {
+		add(s[:1], TokenEnvget)
+		return s[1:], nil
+	}
+
+	for len(s) > 0 && isNameByte(s[0]) {
+		envvar += s[:1]
 		s = s[1:]
-		for len(s) > 0 {
-			r, wid := utf8.DecodeRuneInString(s)
-			s = s[wid:]
-			if r == '}' {
-				w.add(envvar, TokenEnvget)
-				return s, nil
+	}
+	if len(envvar) == 0 {
+		// not a parameter, e.g. "$%"
+		add("$", TokenLiteral)
+		return s, nil
+	}
+	add(envvar, TokenEnvget)
+	return s, nil
+}
+
+// isNameByte returns true if the byte may be in a variable name.
+func isNameByte(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') ||
+		('0' <= c && c <= '9')
+}
+
+// Expand returns the Word as a single string with its variable references
+// and command substitutions replaced by the values from the Expander.
+func (w *Word) Expand(e Expander) (string, error) {
+	s := ""
+	for _, t := range w.Tokens {
+		v, err := t.expand(e)
+		if err != nil {
+			return "", err
+		}
+		s += v
+	}
+	return s, nil
+}
+
+// Pattern returns the expansion of the Word as a shell pattern for Match,
+// with the pattern characters of its quoted Tokens escaped.
+func (w *Word) Pattern(e Expander) (string, error) {
+	s := ""
+	for _, t := range w.Tokens {
+		v, err := t.expand(e)
+		if err != nil {
+			return "", err
+		}
+		if t.Q {
+			v = escapeMeta(v)
+		}
+		s += v
+	}
+	return s, nil
+}
+
+// Fields returns the expansion of the Word split into fields at the IFS
+// characters resulting from unquoted command substitutions. This may return
+// no fields if the Word is only an unquoted substitution with empty output.
+// Fields with unquoted pattern characters are replaced by the sorted names
+// of matching files, if any.
+//
+// If the Expander is also a Paramser, "$@" and unquoted $@ and $* expand to
+// a separate field for each positional parameter, or none if there are no
+// parameters; whereas, "$*" is a single field of the parameters separated
+// by the first character of IFS.
+func (w *Word) Fields(e Expander) ([]string, error) {
+	var (
+		fields  []string
+		field   string
+		pattern string
+		have    bool
+		glob    bool
+	)
+	if len(w.Tokens) == 0 {
+		return []string{""}, nil
+	}
+	ifs := e.Getenv("IFS")
+	if len(ifs) == 0 {
+		ifs = " \t\n"
+	}
+	isIFS := func(r rune) bool { return strings.ContainsRune(ifs, r) }
+	add := func(s string, quoted bool) {
+		field += s
+		if quoted {
+			pattern += escapeMeta(s)
+		} else {
+			pattern += s
+			glob = glob || hasMeta(s)
+		}
+		have = true
+	}
+	flush := func() {
+		var matches []string
+		if glob {
+			matches = Glob(pattern)
+		}
+		if len(matches) > 0 {
+			fields = append(fields, matches...)
+		} else {
+			fields = append(fields, field)
+		}
+		field = ""
+		pattern = ""
+		have = false
+		glob = false
+	}
+	for _, t := range w.Tokens {
+		if p, ok := e.(Paramser); ok && t.T == TokenEnvget &&
+			len(t.Op) == 0 && (t.V == "@" || (t.V == "*" && !t.Q)) {
+			for i, param := range p.Params() {
+				if i > 0 {
+					flush()
+				}
+				add(param, t.Q)
 			}
-			if unicode.IsSpace(r) || strings.ContainsRune("|&;()<>{'\"$/", r) {
-				return "", fmt.Errorf("Unexpected `%c'", r)
+			continue
+		}
+		v, err := t.expand(e)
+		if err != nil {
+			return nil, err
+		}
+		if t.Q || t.T != TokenCmdsub {
+			add(v, t.Q)
+			continue
+		}
+		if len(v) == 0 {
+			continue
+		}
+		parts := strings.FieldsFunc(v, isIFS)
+		first, _ := utf8.DecodeRuneInString(v)
+		last, _ := utf8.DecodeLastRuneInString(v)
+		if have && (len(parts) == 0 || isIFS(first)) {
+			flush()
+		}
+		for i, part := range parts {
+			if i > 0 {
+				flush()
 			}
-			envvar += string(r)
+			add(part, false)
+		}
+		if have && isIFS(last) {
+			flush()
 		}
-		return "", errors.New("Unexpected end-of-line")
 	}
+	if have {
+		flush()
+	}
+	return fields, nil
+}
 
-	for len(s) > 0 {
-		r, wid := utf8.DecodeRuneInString(s)
-		if unicode.IsSpace(r) || strings.ContainsRune("|&;()<>{}'\"$/", r) {
+// assignment returns the variable name and value Tokens if the Word is of
+// the form NAME=VALUE.
+func (w *Word) assignment() (string, []Token) {
+	for i, t := range w.Tokens {
+		if t.T == TokenEnvset {
+			if i == 0 {
+				break
+			}
+			name := ""
+			for _, t := range w.Tokens[:i] {
+				name += t.V
+			}
+			return name, w.Tokens[i+1:]
+		}
+		if t.T != TokenLiteral {
 			break
 		}
-		s = s[wid:]
-		envvar += string(r)
 	}
-	w.add(envvar, TokenEnvget)
-	return s, nil
+	return "", nil
+}
+
+// Unquoted returns true if the Word is the unquoted literal s, e.g. the ')'
+// that ends a case pattern rather than the quoted pattern ")".
+func (w *Word) Unquoted(s string) bool {
+	return len(w.Tokens) == 1 && w.Tokens[0].T == TokenLiteral &&
+		!w.Tokens[0].Q && w.Tokens[0].V == s
 }
 
 func (w *Word) String() string {
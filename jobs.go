@@ -0,0 +1,378 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/platinasystems/goes/internal/shellutils"
+)
+
+// Job is a command list run in the background with the '&' terminator.
+type Job struct {
+	Id  int
+	Cmd string
+
+	mutex   sync.Mutex
+	procs   []*os.Process
+	started chan struct{}
+	done    chan struct{}
+	status  error
+}
+
+type jobs struct {
+	sync.Mutex
+	list   []*Job
+	last   *Job
+	lastId int
+}
+
+// jobProcs collects the processes started by the pipelines of a list, and
+// those of any enclosing list, so that they may be signaled or waited as a
+// background job.
+type jobProcs struct {
+	sync.Mutex
+	parent *jobProcs
+	job    *Job
+}
+
+func (p *jobProcs) add(proc *os.Process) {
+	for ; p != nil; p = p.parent {
+		p.Lock()
+		if p.job != nil {
+			p.job.add(proc)
+		}
+		p.Unlock()
+	}
+}
+
+// background returns true if the list, or an enclosing list, runs as a job.
+func (p *jobProcs) background() bool {
+	for ; p != nil; p = p.parent {
+		p.Lock()
+		job := p.job
+		p.Unlock()
+		if job != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *jobProcs) set(j *Job) {
+	p.Lock()
+	defer p.Unlock()
+	p.job = j
+}
+
+func (j *Job) add(proc *os.Process) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.procs = append(j.procs, proc)
+	if len(j.procs) == 1 {
+		close(j.started)
+	}
+}
+
+func (j *Job) finish(status error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.status = status
+	if len(j.procs) == 0 {
+		close(j.started)
+	}
+	close(j.done)
+}
+
+// Done returns true if the job has finished.
+func (j *Job) Done() bool {
+	select {
+	case <-j.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Pids returns the process ids of the commands forked by the job.
+func (j *Job) Pids() []int {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	pids := make([]int, 0, len(j.procs))
+	for _, proc := range j.procs {
+		pids = append(pids, proc.Pid)
+	}
+	return pids
+}
+
+// Signal the running processes of the job.
+func (j *Job) Signal(sig os.Signal) error {
+	var err error
+	if j.Done() {
+		return fmt.Errorf("%%%d: job has terminated", j.Id)
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	for _, proc := range j.procs {
+		if t := proc.Signal(sig); t != nil && err == nil &&
+			t.Error() != "os: process already finished" {
+			err = t
+		}
+	}
+	return err
+}
+
+// Wait for the job to finish and return its status.
+func (j *Job) Wait() error {
+	<-j.done
+	return j.status
+}
+
+func (j *Job) String() string {
+	state := "Running"
+	if j.Done() {
+		state = "Done"
+		if j.status != nil {
+			state = fmt.Sprint("Exit(", j.status, ")")
+		}
+	}
+	return fmt.Sprintf("[%d] %-24s %s", j.Id, state, j.Cmd)
+}
+
+// Jobs returns the background jobs that haven't been waited or pruned.
+func (g *Goes) Jobs() []*Job {
+	g.jobs.Lock()
+	defer g.jobs.Unlock()
+	return append([]*Job{}, g.jobs.list...)
+}
+
+// FindJob returns the job identified by the given spec, which is one of:
+//
+//	%N	job number N
+//	%% %+	the most recent job
+//	%PREFIX	the most recent job with a command beginning with PREFIX
+//	PID	the job that forked the given process id
+func (g *Goes) FindJob(spec string) (*Job, error) {
+	g.jobs.Lock()
+	defer g.jobs.Unlock()
+	n := len(g.jobs.list)
+	switch {
+	case spec == "%%" || spec == "%+" || spec == "%":
+		if n > 0 {
+			return g.jobs.list[n-1], nil
+		}
+	case strings.HasPrefix(spec, "%"):
+		if id, err := strconv.Atoi(spec[1:]); err == nil {
+			for _, j := range g.jobs.list {
+				if j.Id == id {
+					return j, nil
+				}
+			}
+			break
+		}
+		for i := n - 1; i >= 0; i-- {
+			if strings.HasPrefix(g.jobs.list[i].Cmd, spec[1:]) {
+				return g.jobs.list[i], nil
+			}
+		}
+	default:
+		pid, err := strconv.Atoi(spec)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid job or process id",
+				spec)
+		}
+		for _, j := range g.jobs.list {
+			for _, t := range j.Pids() {
+				if t == pid {
+					return j, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s: no such job", spec)
+}
+
+// WaitJob waits for the job to finish then removes it from the job table.
+// It returns the job's exit status, e.g. 128 plus the number of the signal
+// that killed it, as a quiet ExitStatus since the job has reported any
+// error itself. The receipt of a trapped signal instead runs its trap and
+// returns 128 plus the signal number as the status.
+func (g *Goes) WaitJob(j *Job) error {
+	select {
+	case <-j.done:
+	case sig := <-g.caught():
+		g.runSignalTrap(sig)
+		return ExitStatus(128 + int(sig.(syscall.Signal)))
+	}
+	var err error
+	if j.status != nil {
+		err = ExitStatus(ExitCode(j.status))
+	}
+	g.jobs.Lock()
+	defer g.jobs.Unlock()
+	for i, t := range g.jobs.list {
+		if t == j {
+			g.jobs.list = append(g.jobs.list[:i],
+				g.jobs.list[i+1:]...)
+			break
+		}
+	}
+	if len(g.jobs.list) == 0 {
+		g.jobs.lastId = 0
+	}
+	return err
+}
+
+// PruneJobs removes finished jobs from the job table.
+func (g *Goes) PruneJobs() {
+	g.jobs.Lock()
+	defer g.jobs.Unlock()
+	list := g.jobs.list[:0]
+	for _, j := range g.jobs.list {
+		if !j.Done() {
+			list = append(list, j)
+		}
+	}
+	g.jobs.list = list
+	if len(list) == 0 {
+		g.jobs.lastId = 0
+	}
+}
+
+func (g *Goes) newJob(cmd string) *Job {
+	g.jobs.Lock()
+	defer g.jobs.Unlock()
+	g.jobs.lastId++
+	j := &Job{
+		Id:      g.jobs.lastId,
+		Cmd:     cmd,
+		started: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	g.jobs.list = append(g.jobs.list, j)
+	g.jobs.last = j
+	return j
+}
+
+// lastPid returns the process id of the last command forked by the most
+// recent background job, or an empty string if there isn't one.
+func (g *Goes) lastPid() string {
+	g.jobs.Lock()
+	j := g.jobs.last
+	g.jobs.Unlock()
+	if j == nil {
+		return ""
+	}
+	pids := j.Pids()
+	if len(pids) == 0 {
+		return ""
+	}
+	return strconv.Itoa(pids[len(pids)-1])
+}
+
+// makeJobFunc returns a list runner that runs the given list in the
+// background as a new job with stdin redirected from /dev/null. Each run
+// reprocesses the list, with the lines that its blocks read, on a child of
+// the shell so that the job doesn't share its variables, status, or loop.
+func (g *Goes) makeJobFunc(ls shellutils.List, lines []string, line int, parent *jobProcs, cmd string) func(io.Reader, io.Writer, io.Writer) error {
+	return func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+		procs := &jobProcs{parent: parent}
+		c := g.child(lines, line, procs)
+		ls := shellutils.List{
+			Cmds: append([]shellutils.Cmdline{}, ls.Cmds...),
+			Pos:  ls.Pos,
+		}
+		_, _, listfun, err := c.processList(ls)
+		if err != nil {
+			return err
+		}
+		j := g.newJob(cmd)
+		procs.set(j)
+		go func() {
+			in, err := os.Open(os.DevNull)
+			if err != nil {
+				j.finish(err)
+				return
+			}
+			defer in.Close()
+			err = listfun(in, stdout, stderr)
+			if err == nil {
+				err = c.Status
+			}
+			j.finish(err)
+		}()
+		// wait for the first fork, or completion, so the job's
+		// processes are available to $!, jobs, kill, and wait
+		<-j.started
+		g.Status = nil
+		return nil
+	}
+}
+
+// child returns a copy of the shell's variables, parameters, options,
+// aliases, and functions for a job. It reads the given lines, from the
+// line number, then any that follow, e.g. for a heredoc.
+func (g *Goes) child(lines []string, line int, procs *jobProcs) *Goes {
+	c := &Goes{
+		NAME:         g.NAME,
+		USAGE:        g.USAGE,
+		APROPOS:      g.APROPOS,
+		MAN:          g.MAN,
+		ByName:       g.ByName,
+		CatlineStdin: g.CatlineStdin,
+		Status:       g.Status,
+		Verbosity:    g.Verbosity,
+		args:         append([]string{}, g.args...),
+		options:      g.options,
+		parent:       g.parent,
+		procs:        procs,
+	}
+	if g.EnvMap != nil {
+		c.EnvMap = make(map[string]string, len(g.EnvMap))
+		for k, v := range g.EnvMap {
+			c.EnvMap[k] = v
+		}
+	}
+	if g.aliases != nil {
+		c.aliases = make(map[string]string, len(g.aliases))
+		for k, v := range g.aliases {
+			c.aliases[k] = v
+		}
+	}
+	for _, s := range g.functions.scopes {
+		t := make(scope, len(s))
+		for k, v := range s {
+			t[k] = v
+		}
+		c.functions.scopes = append(c.functions.scopes, t)
+	}
+	// redefine the functions to run, as the job, on the child
+	for name, f := range g.FunctionMap {
+		def := append([]string{"function " + name + " {"},
+			f.Definition...)
+		c.eval(strings.Join(append(def, "}"), "\n"), ioutil.Discard)
+	}
+	catline := g.Catline
+	c.Catline = func(prompt string) (string, error) {
+		if len(lines) > 0 {
+			s := lines[0]
+			lines = lines[1:]
+			return s, nil
+		}
+		if catline == nil {
+			return "", io.EOF
+		}
+		return catline(prompt)
+	}
+	c.Line = line
+	return c
+}
@@ -0,0 +1,81 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import "errors"
+
+var errNotInLoop = errors.New("only meaningful in a `for', `while', or `until' loop")
+
+// loop records the nesting of running loop blocks and any break or continue
+// that is unwinding to an enclosing loop.
+type loop struct {
+	depth int
+	brk   int
+	cont  int
+}
+
+// EnterLoop is called by loop blocks (e.g. while, until, for) before the
+// first pass of the loop and must be paired with ExitLoop.
+func (g *Goes) EnterLoop() {
+	g.loop.depth++
+}
+
+func (g *Goes) ExitLoop() {
+	g.loop.depth--
+	if g.loop.depth == 0 {
+		g.loop.brk = 0
+		g.loop.cont = 0
+	}
+}
+
+// EndOfPass is called by loop blocks after each pass through their body.
+// It returns true if the loop should terminate because of a pending break,
+// or continue of an outer loop, return, or errexit.
+func (g *Goes) EndOfPass() bool {
+	if g.Exiting() || g.Returning() {
+		return true
+	}
+	if g.loop.brk > 0 {
+		g.loop.brk--
+		return true
+	}
+	if g.loop.cont > 0 {
+		g.loop.cont--
+		return g.loop.cont > 0
+	}
+	return false
+}
+
+// Jumping returns true while a break or continue is unwinding to its loop,
+// a return to its function call, or errexit the shell, so that block and
+// list runners stop executing commands.
+func (g *Goes) Jumping() bool {
+	return g.loop.brk > 0 || g.loop.cont > 0 || g.Returning() ||
+		g.Exiting()
+}
+
+// Break the N'th enclosing loop.
+func (g *Goes) Break(n int) error {
+	if g.loop.depth == 0 {
+		return errNotInLoop
+	}
+	if n > g.loop.depth {
+		n = g.loop.depth
+	}
+	g.loop.brk = n
+	return nil
+}
+
+// Continue with the next pass of the N'th enclosing loop.
+func (g *Goes) Continue(n int) error {
+	if g.loop.depth == 0 {
+		return errNotInLoop
+	}
+	if n > g.loop.depth {
+		n = g.loop.depth
+	}
+	g.loop.cont = n
+	return nil
+}
@@ -0,0 +1,167 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/platinasystems/goes/internal/shellutils"
+)
+
+// options are the shell modes of the set builtin; functions and sourced
+// scripts share those of their caller.
+type options struct {
+	errexit  bool
+	nounset  bool
+	pipefail bool
+	// cond is non-zero while running the condition of a block, e.g.
+	// if or while, where a failure doesn't exit with errexit or run the
+	// ERR trap
+	cond int
+	// exiting is true while errexit is unwinding the shell after a
+	// command failure
+	exiting bool
+	// interactive is true if the shell reads commands from a user
+	interactive bool
+}
+
+// Option names in the order reported by set -o.
+var optionNames = []string{"errexit", "nounset", "pipefail", "xtrace"}
+
+// OptionNames returns the names of the shell options.
+func OptionNames() []string {
+	return append([]string{}, optionNames...)
+}
+
+// Option returns true if the named shell option is on.
+func (g *Goes) Option(name string) (bool, error) {
+	switch name {
+	case "errexit":
+		return g.options.errexit, nil
+	case "nounset":
+		return g.options.nounset, nil
+	case "pipefail":
+		return g.options.pipefail, nil
+	case "xtrace":
+		return g.Verbosity >= VerboseVerify, nil
+	}
+	return false, fmt.Errorf("%s: invalid option name", name)
+}
+
+// SetOption turns the named shell option on or off. The xtrace option is
+// the same as VerboseVerify, or greater, Verbosity.
+func (g *Goes) SetOption(name string, on bool) error {
+	switch name {
+	case "errexit":
+		g.options.errexit = on
+	case "nounset":
+		g.options.nounset = on
+	case "pipefail":
+		g.options.pipefail = on
+	case "xtrace":
+		if on && g.Verbosity < VerboseVerify {
+			g.Verbosity = VerboseVerify
+		} else if !on && g.Verbosity >= VerboseVerify {
+			g.Verbosity = VerboseQuiet
+		}
+	default:
+		return fmt.Errorf("%s: invalid option name", name)
+	}
+	return nil
+}
+
+// Nounset returns true if the expansion of an unset variable is an error.
+func (g *Goes) Nounset() bool { return g.options.nounset }
+
+// LookupEnv is Getenv that also returns whether the named parameter or
+// variable is set.
+func (g *Goes) LookupEnv(k string) (string, bool) {
+	if v, found := g.param(k); found {
+		switch {
+		case k == "!":
+			return v, len(v) > 0
+		case k != "0" && len(k) > 0 && k[0] >= '0' && k[0] <= '9':
+			i, _ := strconv.Atoi(k)
+			return v, i <= len(g.Params())
+		}
+		return v, true
+	}
+	if v, def := g.EnvMap[k]; def {
+		return v, true
+	}
+	return os.LookupEnv(k)
+}
+
+// EnterCondition is called by blocks before running a condition list, e.g.
+// of if, while, or until, and must be paired with ExitCondition.
+func (g *Goes) EnterCondition() {
+	g.options.cond++
+}
+
+func (g *Goes) ExitCondition() {
+	g.options.cond--
+}
+
+// Exiting returns true while the shell is unwinding with errexit after a
+// command failure, so that block and list runners stop executing commands.
+func (g *Goes) Exiting() bool {
+	return g.options.exiting
+}
+
+// Exit the shell with the given status after running any EXIT trap; or,
+// that of a command substitution, unwind its subshell.
+func (g *Goes) Exit(code int) {
+	if code != 0 {
+		g.Status = ExitStatus(code)
+	} else {
+		g.Status = nil
+	}
+	if g.subshell {
+		g.options.exiting = true
+		return
+	}
+	g.RunTrap("EXIT")
+	os.Exit(code)
+}
+
+// errexit runs any ERR trap if the last command failed outside of a
+// condition; then, with the errexit option, starts unwinding the shell.
+func (g *Goes) errexit() {
+	if g.Status == nil || g.options.cond > 0 || g.Returning() {
+		return
+	}
+	g.RunTrap("ERR")
+	if g.options.errexit {
+		g.options.exiting = true
+	}
+}
+
+// SetInteractive marks the shell as reading commands from a user, e.g. at
+// the prompt of the cli, which an expansion error doesn't exit. It returns
+// the prior mode to restore.
+func (g *Goes) SetInteractive(on bool) bool {
+	prior := g.options.interactive
+	g.options.interactive = on
+	return prior
+}
+
+// unset starts unwinding a non-interactive shell, as with errexit, after
+// the expansion of an unset variable with the nounset option or of
+// ${NAME:?WORD} with NAME unset or empty.
+func (g *Goes) unset(err error) {
+	if g.options.interactive {
+		return
+	}
+	switch err.(type) {
+	case *shellutils.UnboundError:
+		if g.options.nounset {
+			g.options.exiting = true
+		}
+	case *shellutils.NullError:
+		g.options.exiting = true
+	}
+}
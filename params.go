@@ -0,0 +1,131 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/platinasystems/goes/internal/prog"
+)
+
+// Params returns the positional parameters, $1 through $N, of the running
+// script or function.
+func (g *Goes) Params() []string {
+	if len(g.args) == 0 {
+		return nil
+	}
+	return g.args[1:]
+}
+
+// SetParams replaces $0 and the positional parameters with args and returns
+// the previous for the caller to restore.
+func (g *Goes) SetParams(args ...string) []string {
+	saved := g.args
+	g.args = args
+	return saved
+}
+
+// Shift removes the first n positional parameters.
+func (g *Goes) Shift(n int) error {
+	params := g.Params()
+	if n < 0 || n > len(params) {
+		return fmt.Errorf("%d: shift count out of range", n)
+	}
+	g.args = append(g.args[:1:1], params[n:]...)
+	return nil
+}
+
+// param returns the value of the named special or positional parameter and
+// true; or "", false if the name isn't that of a parameter.
+func (g *Goes) param(k string) (string, bool) {
+	switch k {
+	case "#":
+		return strconv.Itoa(len(g.Params())), true
+	case "?":
+		return strconv.Itoa(ExitCode(g.Status)), true
+	case "$":
+		return strconv.Itoa(os.Getpid()), true
+	case "!":
+		return g.lastPid(), true
+	case "@":
+		return strings.Join(g.Params(), " "), true
+	case "*":
+		sep := " "
+		if ifs, found := g.EnvMap["IFS"]; found {
+			sep = ifs
+			if len(sep) > 1 {
+				sep = sep[:1]
+			}
+		}
+		return strings.Join(g.Params(), sep), true
+	case "0":
+		if len(g.args) > 0 {
+			return g.args[0], true
+		}
+		return prog.Base(), true
+	}
+	if i := strings.IndexByte(k, '['); i > 0 && strings.HasSuffix(k, "]") {
+		return g.element(k[:i], k[i+1:len(k)-1]), true
+	}
+	i, err := strconv.Atoi(k)
+	if err != nil || i < 1 {
+		return "", false
+	}
+	if params := g.Params(); i <= len(params) {
+		return params[i-1], true
+	}
+	return "", true
+}
+
+// element returns the subscripted field of an array-like variable, e.g.
+// ${PIPESTATUS[0]}, with fields separated by white space; or the whole value
+// for the subscript @ or *.
+func (g *Goes) element(name, subscript string) string {
+	v := g.Getenv(name)
+	if subscript == "@" || subscript == "*" {
+		return v
+	}
+	i, err := strconv.Atoi(subscript)
+	if fields := strings.Fields(v); err == nil && i >= 0 && i < len(fields) {
+		return fields[i]
+	}
+	return ""
+}
+
+// ExitStatus is the error of a command that fails, without a message, with
+// the given exit status.
+type ExitStatus int
+
+func (s ExitStatus) Error() string { return fmt.Sprint("exit status ", int(s)) }
+
+func (s ExitStatus) ExitCode() int { return int(s) }
+
+// ExitCode returns the shell exit status of a command's error: 0 if nil; the
+// exit code, or 128 plus the signal number, of a process; the ExitCode of an
+// error that has that method; otherwise 1.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if x, ok := err.(*exec.ExitError); ok {
+		if ws, ok := x.Sys().(syscall.WaitStatus); ok {
+			if ws.Signaled() {
+				return 128 + int(ws.Signal())
+			}
+			return ws.ExitStatus()
+		}
+	}
+	if method, ok := err.(interface {
+		ExitCode() int
+	}); ok {
+		return method.ExitCode()
+	}
+	return 1
+}
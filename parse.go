@@ -0,0 +1,29 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"io"
+
+	"github.com/platinasystems/goes/internal/shellutils"
+)
+
+// Parse returns the next command list read with Catline; the positions of
+// its words follow the Line count of previous reads.
+func (g *Goes) Parse(prompt string) (*shellutils.List, error) {
+	return shellutils.ParseAt(prompt, g.Catline, &g.Line)
+}
+
+// ParseBlock is Parse for the rest of a block that began at pos, e.g. with
+// if, so the end of input before its closing keyword, e.g. fi, is a syntax
+// error.
+func (g *Goes) ParseBlock(prompt string, pos shellutils.Pos, end string) (*shellutils.List, error) {
+	ls, err := g.Parse(prompt)
+	if err == io.EOF {
+		err = pos.Errorf("Unexpected EOF while looking for matching `%s'",
+			end)
+	}
+	return ls, err
+}
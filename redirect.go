@@ -0,0 +1,208 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/platinasystems/goes/internal/shellutils"
+	"github.com/platinasystems/goes/internal/url"
+)
+
+// redirect returns the stdin, stdout, and stderr of a command after applying
+// its redirections in order. Opened files are appended to closers.
+func (g *Goes) redirect(redirects []shellutils.Redirect, stdin io.Reader, stdout, stderr io.Writer, closers *[]io.Closer) (io.Reader, io.Writer, io.Writer, error) {
+	in := stdin
+	outs := []io.Writer{stdout, stderr}
+	for _, r := range redirects {
+		switch r.Op {
+		case "<", "<<", "<<-", "<<<", "<&":
+			if r.Fd != 0 {
+				return nil, nil, nil,
+					fmt.Errorf("%d%s: unsupported", r.Fd, r.Op)
+			}
+		default:
+			if r.Fd != -1 && r.Fd != 1 && r.Fd != 2 {
+				return nil, nil, nil,
+					fmt.Errorf("%d%s: unsupported", r.Fd, r.Op)
+			}
+		}
+		switch r.Op {
+		case "<":
+			rc, err := url.Open(r.Target)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			in = rc
+			*closers = append(*closers, rc)
+		case "<<", "<<-":
+			pr, pw, err := os.Pipe()
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			// the shell reads the next command once the document
+			// has been read
+			done := make(waiter)
+			in = pr
+			*closers = append(*closers, pr, done)
+			go func(label string, trim bool) {
+				defer close(done)
+				g.heredoc(pw, label, trim)
+			}(r.Target, r.Op == "<<-")
+		case "<<<":
+			in = strings.NewReader(r.Target + "\n")
+		case "<&":
+			if r.Target != "0" {
+				return nil, nil, nil,
+					fmt.Errorf("<&%s: unsupported", r.Target)
+			}
+		case ">&":
+			switch r.Target {
+			case "1":
+				outs[r.Fd-1] = outs[0]
+			case "2":
+				outs[r.Fd-1] = outs[1]
+			default:
+				return nil, nil, nil,
+					fmt.Errorf(">&%s: bad file descriptor",
+						r.Target)
+			}
+		case ">", ">>>", "&>":
+			wc, err := url.Create(r.Target)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			*closers = append(*closers, wc)
+			setOutput(outs, r, wc)
+		case ">>", ">>>>", "&>>":
+			wc, err := url.Append(r.Target)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			*closers = append(*closers, wc)
+			setOutput(outs, r, wc)
+		}
+	}
+	return in, outs[0], outs[1], nil
+}
+
+func setOutput(outs []io.Writer, r shellutils.Redirect, w io.Writer) {
+	switch {
+	case r.Fd == -1:
+		outs[0] = w
+		outs[1] = w
+	case r.Op == ">>>" || r.Op == ">>>>":
+		outs[r.Fd-1] = io.MultiWriter(outs[r.Fd-1], w)
+	default:
+		outs[r.Fd-1] = w
+	}
+}
+
+// waiter is closed by a goroutine; its Close waits for that.
+type waiter chan struct{}
+
+func (w waiter) Close() error {
+	<-w
+	return nil
+}
+
+// redirectBlock returns the runfun of a block, e.g. while, with the
+// redirections following its closing keyword, e.g. "done < FILE".
+func (g *Goes) redirectBlock(cl shellutils.Cmdline, runfun func(io.Reader, io.Writer, io.Writer, bool, bool) error, closers *[]io.Closer) func(io.Reader, io.Writer, io.Writer, bool, bool) error {
+	cl.Cmds = cl.Cmds[1:]
+	return func(stdin io.Reader, stdout io.Writer, stderr io.Writer, isFirst bool, isLast bool) error {
+		_, _, redirects, err := cl.SliceRedirects(g)
+		if err != nil {
+			return err
+		}
+		in, out, errout, err := g.redirect(redirects, stdin, stdout,
+			stderr, closers)
+		if err != nil {
+			return err
+		}
+		return runfun(in, out, errout, isFirst, isLast)
+	}
+}
+
+// heredoc copies the lines read up to the label to the writer.
+func (g *Goes) heredoc(w io.WriteCloser, label string, trim bool) {
+	defer w.Close()
+	prompt := "<<" + label + " "
+	for {
+		s, err := g.Catline(prompt)
+		if err != nil {
+			break
+		}
+		g.Line++
+		if trim {
+			s = strings.TrimLeft(s, " \t")
+		}
+		if s == label {
+			break
+		}
+		fmt.Fprintln(w, s)
+	}
+}
+
+// stdio runs a command that doesn't fork with os.Stdin, os.Stdout, and
+// os.Stderr replaced by the redirected stdin, stdout, and stderr. Those that
+// aren't files are copied through pipes.
+func stdio(stdin io.Reader, stdout, stderr io.Writer, main func() error) error {
+	var (
+		wg    sync.WaitGroup
+		pipes []*os.File
+	)
+	saved := []*os.File{os.Stdin, os.Stdout, os.Stderr}
+	defer func() {
+		os.Stdin, os.Stdout, os.Stderr = saved[0], saved[1], saved[2]
+		for _, f := range pipes {
+			f.Close()
+		}
+		wg.Wait()
+	}()
+	if f, ok := stdin.(*os.File); ok {
+		os.Stdin = f
+	} else {
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			return err
+		}
+		pipes = append(pipes, pr)
+		go func() {
+			io.Copy(pw, stdin)
+			pw.Close()
+		}()
+		os.Stdin = pr
+	}
+	for _, p := range []struct {
+		w io.Writer
+		f **os.File
+	}{
+		{stdout, &os.Stdout},
+		{stderr, &os.Stderr},
+	} {
+		if f, ok := p.w.(*os.File); ok {
+			*p.f = f
+			continue
+		}
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			return err
+		}
+		pipes = append(pipes, pw)
+		wg.Add(1)
+		go func(w io.Writer) {
+			defer wg.Done()
+			io.Copy(w, pr)
+			pr.Close()
+		}(p.w)
+		*p.f = pw
+	}
+	return main()
+}
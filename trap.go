@@ -0,0 +1,259 @@
+// Copyright © 2017 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// trapSignals are the signals that may be trapped by name.
+var trapSignals = map[string]syscall.Signal{
+	"HUP":    syscall.SIGHUP,
+	"INT":    syscall.SIGINT,
+	"QUIT":   syscall.SIGQUIT,
+	"USR1":   syscall.SIGUSR1,
+	"USR2":   syscall.SIGUSR2,
+	"PIPE":   syscall.SIGPIPE,
+	"ALRM":   syscall.SIGALRM,
+	"TERM":   syscall.SIGTERM,
+	"CHLD":   syscall.SIGCHLD,
+	"CONT":   syscall.SIGCONT,
+	"TSTP":   syscall.SIGTSTP,
+	"TTIN":   syscall.SIGTTIN,
+	"TTOU":   syscall.SIGTTOU,
+	"WINCH":  syscall.SIGWINCH,
+	"XCPU":   syscall.SIGXCPU,
+	"XFSZ":   syscall.SIGXFSZ,
+	"VTALRM": syscall.SIGVTALRM,
+	"PROF":   syscall.SIGPROF,
+}
+
+// traps are the command lists that the shell runs on receipt of a signal,
+// or on the EXIT and ERR conditions, by their trap name.
+type traps struct {
+	sync.Mutex
+	list map[string]string
+	// ignored are the signals that were ignored before being trapped
+	ignored map[string]bool
+	// running are the traps with a running list that mustn't recurse
+	running map[string]bool
+	sig     chan os.Signal
+	// discard receives the signals that the shell, but not its children,
+	// ignores
+	discard   chan os.Signal
+	discarded map[syscall.Signal]bool
+}
+
+// TrapName returns the canonical name of a trap condition, e.g. EXIT, ERR,
+// or INT from any of "int", "SIGINT", or "2".
+func TrapName(s string) (string, error) {
+	name := strings.TrimPrefix(strings.ToUpper(s), "SIG")
+	switch name {
+	case "0":
+		return "EXIT", nil
+	case "EXIT", "ERR":
+		return name, nil
+	}
+	if n, err := strconv.Atoi(name); err == nil {
+		for t, sig := range trapSignals {
+			if int(sig) == n {
+				return t, nil
+			}
+		}
+	} else if _, found := trapSignals[name]; found {
+		return name, nil
+	}
+	return "", fmt.Errorf("%s: invalid signal specification", s)
+}
+
+// Signal returns the signal named as for TrapName, or KILL or STOP, which
+// can't be trapped.
+func Signal(s string) (syscall.Signal, error) {
+	name := strings.TrimPrefix(strings.ToUpper(s), "SIG")
+	switch name {
+	case "KILL":
+		return syscall.SIGKILL, nil
+	case "STOP":
+		return syscall.SIGSTOP, nil
+	}
+	if n, err := strconv.Atoi(name); err == nil {
+		if n > 0 && n < 65 {
+			return syscall.Signal(n), nil
+		}
+	} else if sig, found := trapSignals[name]; found {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("%s: invalid signal specification", s)
+}
+
+// Trap sets the command list to run on the named condition. An empty list
+// ignores the signal.
+func (g *Goes) Trap(list, name string) error {
+	name, err := TrapName(name)
+	if err != nil {
+		return err
+	}
+	g.traps.Lock()
+	defer g.traps.Unlock()
+	if g.traps.list == nil {
+		g.traps.list = make(map[string]string)
+		g.traps.ignored = make(map[string]bool)
+		g.traps.running = make(map[string]bool)
+	}
+	if sig, found := trapSignals[name]; found {
+		if _, trapped := g.traps.list[name]; !trapped {
+			g.traps.ignored[name] = signal.Ignored(sig)
+		}
+		if len(list) == 0 {
+			signal.Ignore(sig)
+		} else {
+			if g.traps.sig == nil {
+				g.traps.sig = make(chan os.Signal, 8)
+			}
+			signal.Notify(g.traps.sig, sig)
+		}
+	}
+	g.traps.list[name] = list
+	return nil
+}
+
+// Untrap restores the default action of the named condition.
+func (g *Goes) Untrap(name string) error {
+	name, err := TrapName(name)
+	if err != nil {
+		return err
+	}
+	g.traps.Lock()
+	defer g.traps.Unlock()
+	if _, trapped := g.traps.list[name]; !trapped {
+		return nil
+	}
+	if sig, found := trapSignals[name]; found {
+		signal.Reset(sig)
+		if g.traps.ignored[name] {
+			signal.Ignore(sig)
+		} else if g.traps.discarded[sig] {
+			signal.Notify(g.traps.discard, sig)
+		}
+	}
+	delete(g.traps.list, name)
+	return nil
+}
+
+// Traps returns the command lists of the trapped conditions by name.
+func (g *Goes) Traps() map[string]string {
+	g.traps.Lock()
+	defer g.traps.Unlock()
+	m := make(map[string]string, len(g.traps.list))
+	for name, list := range g.traps.list {
+		m[name] = list
+	}
+	return m
+}
+
+// TrapNames returns the sorted names of the conditions that may be
+// trapped, with EXIT first, then the signals by number, and ERR last.
+func TrapNames() []string {
+	names := make([]string, 0, len(trapSignals)+2)
+	for name := range trapSignals {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return trapSignals[names[i]] < trapSignals[names[j]]
+	})
+	return append(append([]string{"EXIT"}, names...), "ERR")
+}
+
+// IgnoreSignal ignores the signal unless the shell has a trap for it. The
+// signal is caught and discarded rather than set to SIG_IGN, so that it
+// has its default action in the programs run by the shell; whereas those
+// ignored by a trap with an empty list remain ignored in them.
+func (g *Goes) IgnoreSignal(sig syscall.Signal) {
+	g.traps.Lock()
+	defer g.traps.Unlock()
+	if g.traps.discard == nil {
+		g.traps.discard = make(chan os.Signal, 1)
+		g.traps.discarded = make(map[syscall.Signal]bool)
+	}
+	g.traps.discarded[sig] = true
+	for name, t := range trapSignals {
+		if t == sig {
+			if _, trapped := g.traps.list[name]; trapped {
+				return
+			}
+		}
+	}
+	signal.Notify(g.traps.discard, sig)
+}
+
+// RunTrap runs the command list of the named condition, if any, without
+// changing the exit status, $?, of the last command. The EXIT trap is run
+// at most once.
+func (g *Goes) RunTrap(name string) {
+	g.traps.Lock()
+	list, found := g.traps.list[name]
+	if !found || len(list) == 0 || g.traps.running[name] {
+		g.traps.Unlock()
+		return
+	}
+	g.traps.running[name] = true
+	if name == "EXIT" {
+		delete(g.traps.list, name)
+	}
+	g.traps.Unlock()
+	defer func() {
+		g.traps.Lock()
+		delete(g.traps.running, name)
+		g.traps.Unlock()
+	}()
+	// an EXIT or ERR trap runs while errexit is unwinding the shell
+	status, exiting := g.Status, g.options.exiting
+	g.options.exiting = false
+	if err := g.eval(list, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "trap:", err)
+	}
+	g.Status = status
+	g.options.exiting = g.options.exiting || exiting
+}
+
+// caught returns the channel of trapped signals that have been received.
+func (g *Goes) caught() chan os.Signal {
+	g.traps.Lock()
+	defer g.traps.Unlock()
+	return g.traps.sig
+}
+
+// runSignalTrap runs the trap of a received signal.
+func (g *Goes) runSignalTrap(sig os.Signal) {
+	for name, t := range trapSignals {
+		if t == sig {
+			g.RunTrap(name)
+			return
+		}
+	}
+}
+
+// runSignalTraps runs the traps of any received signals.
+func (g *Goes) runSignalTraps() {
+	ch := g.caught()
+	if ch == nil {
+		return
+	}
+	for {
+		select {
+		case sig := <-ch:
+			g.runSignalTrap(sig)
+		default:
+			return
+		}
+	}
+}